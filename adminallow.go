@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// adminAllow は -admin-allow の CIDR。空なら制限しない
+var adminAllow []*net.IPNet
+
+// parseCIDRList は "203.0.113.0/24,10.0.0.1" のような一覧を読む。/ のない IP は単一のアドレスとして扱う
+func parseCIDRList(s string) ([]*net.IPNet, error) {
+	var nets []*net.IPNet
+	for _, item := range splitList(s) {
+		if !strings.Contains(item, "/") {
+			if ip := net.ParseIP(item); ip != nil && ip.To4() != nil {
+				item += "/32"
+			} else {
+				item += "/128"
+			}
+		}
+		_, n, err := net.ParseCIDR(item)
+		if err != nil {
+			return nil, err
+		}
+		nets = append(nets, n)
+	}
+	return nets, nil
+}
+
+// adminAddrAllowed は接続元のアドレス（X-Forwarded-For は使わない。PROXY protocol 有効時はその送信元）が
+// -admin-allow に含まれるかを返す
+func adminAddrAllowed(addr string) bool {
+	if len(adminAllow) == 0 {
+		return true
+	}
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, n := range adminAllow {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// adminGate は管理用のパスへのリクエストを許可するかを決める。許可しなければ応答を書いて false。
+// 許可したときは認証した相手を入れたリクエストを返すので、以降はそちらを使う。
+// 許可されていない送信元には管理画面があること自体を見せないよう 404 を返す。
+// ブラウザからの状態を変えるリクエストは CSRF トークンも確かめる
+func adminGate(w http.ResponseWriter, r *http.Request) (*http.Request, bool) {
+	if !adminAddrAllowed(r.RemoteAddr) {
+		http.NotFound(w, r)
+		return r, false
+	}
+	r, ok := adminAuthorized(w, r)
+	if !ok {
+		return r, false
+	}
+	if !csrfValid(r) {
+		http.Error(w, "invalid csrf token", http.StatusForbidden)
+		return r, false
+	}
+	return r, true
+}
+
+// grpcAddrAllowed は gRPC の接続元を -admin-allow で確かめる
+func grpcAddrAllowed(ctx context.Context) error {
+	if len(adminAllow) == 0 {
+		return nil
+	}
+	if p, ok := peer.FromContext(ctx); ok && adminAddrAllowed(p.Addr.String()) {
+		return nil
+	}
+	return status.Error(codes.PermissionDenied, "forbidden")
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// handleAdminStream は GET /admin/stream で、新しいエントリを管理画面のカード HTML にして SSE で送る。
+// ページ側はそのまま先頭に差し込むだけで済む
+func handleAdminStream(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	ch := hub.subscribe()
+	defer hub.unsubscribe(ch)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	fmt.Fprint(w, ": connected\n\n")
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case entry := <-ch:
+			var card bytes.Buffer
+			if err := tmpl.ExecuteTemplate(&card, "card", entry); err != nil {
+				fmt.Printf("Template Error: %v\n", err)
+				continue
+			}
+			fmt.Fprintf(w, "id: %d\nevent: card\n", entry.ID)
+			// SSE では CR も改行として扱われるので落としておく
+			html := strings.ReplaceAll(strings.TrimSpace(card.String()), "\r", "")
+			for _, line := range strings.Split(html, "\n") {
+				fmt.Fprintf(w, "data: %s\n", line)
+			}
+			fmt.Fprint(w, "\n")
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": ping\n\n")
+		case <-r.Context().Done():
+			return
+		}
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+)
+
+// adminTLSOnly は -admin-tls-port 指定時に true になり、他のリスナーでは管理用のパスを 404 にする
+var adminTLSOnly bool
+
+// adminTLSConfig はクライアント証明書を必須にする TLS 設定を作る。
+// certFile が空なら自己署名証明書を使う
+func adminTLSConfig(certFile, keyFile, caFile string, sans []string) (*tls.Config, error) {
+	pem, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("%s: no certificates found", caFile)
+	}
+	var config *tls.Config
+	if certFile == "" {
+		config, err = selfSignedTLSConfig(sans)
+	} else {
+		config, err = loadTLSConfig(certFile, keyFile)
+	}
+	if err != nil {
+		return nil, err
+	}
+	config.ClientAuth = tls.RequireAndVerifyClientCert
+	config.ClientCAs = pool
+	config.MinVersion = tls.VersionTLS12
+	return config, nil
+}
+
+// startAdminTLSServer は管理画面と API だけを mTLS で待ち受ける。記録用のパスはここでは扱わない
+func startAdminTLSServer(port string, config *tls.Config) {
+	server := &http.Server{
+		Addr:      ":" + port,
+		TLSConfig: config,
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !isAdminPath(r.URL.Path) {
+				http.NotFound(w, r)
+				return
+			}
+			if r, ok := adminGate(w, r); ok {
+				http.DefaultServeMux.ServeHTTP(w, r)
+			}
+		}),
+	}
+	if err := server.ListenAndServeTLS("", ""); err != nil {
+		fmt.Printf("Admin TLS Error: %v\n", err)
+	}
+}
@@ -0,0 +1,325 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	apiDefaultLimit = 50
+	apiMaxLimit     = 1000
+)
+
+// logFilter は /api/logs や /admin のクエリで指定する絞り込み条件。空の項目は使わない
+type logFilter struct {
+	ip     string
+	method string // 大文字小文字を区別しない
+	host   string // ポートを除いた Host の完全一致（大文字小文字を区別しない）
+	path   string // HTTP のパスの前方一致
+	tag    string
+	token  string
+	// campaign はそのキャンペーンに紐づけた相関トークン宛てのもの。紐づけはその時点のものを見る
+	campaign string
+	unread   bool
+	// archived は "0" ならアーカイブしたものを除き、"1" ならアーカイブしたものだけ、空なら区別しない。
+	// クエリで指定がなければ "0" にする
+	archived string
+	starred  bool
+	// starredFirst はスター付きを先頭に並べる。管理画面で使う
+	starredFirst bool
+	since        time.Time
+	until        time.Time
+	query        string         // RawRequest の部分一致
+	re           *regexp.Regexp // regex=1 のときは query を正規表現として使う
+}
+
+// parseLogFilter は ip / method / host / path / tag / token / campaign / unread / starred / archived / from(since) / to / q / regex を読む
+func parseLogFilter(r *http.Request) (logFilter, error) {
+	q := r.URL.Query()
+	f := logFilter{ip: q.Get("ip"), method: q.Get("method"), host: q.Get("host"), path: q.Get("path"), tag: q.Get("tag"), token: q.Get("token"), campaign: q.Get("campaign"), unread: q.Get("unread") == "1", starred: q.Get("starred") == "1", query: q.Get("q")}
+	switch q.Get("archived") {
+	case "":
+		f.archived = "0"
+	case "1":
+		f.archived = "1"
+	case "all":
+	default:
+		return f, fmt.Errorf("invalid archived: %q (1 or all)", q.Get("archived"))
+	}
+	if f.query != "" && q.Get("regex") == "1" {
+		re, err := regexp.Compile(f.query)
+		if err != nil {
+			return f, fmt.Errorf("invalid regex: %w", err)
+		}
+		f.re = re
+	}
+	from := q.Get("from")
+	if from == "" {
+		from = q.Get("since")
+	}
+	var err error
+	if f.since, err = parseFilterTime(from, false); err != nil {
+		return f, fmt.Errorf("invalid from: %w", err)
+	}
+	if f.until, err = parseFilterTime(q.Get("to"), true); err != nil {
+		return f, fmt.Errorf("invalid to: %w", err)
+	}
+	return f, nil
+}
+
+// parseFilterTime は RFC3339、2006-01-02 形式の日付（ローカル時刻）、UNIX 秒、または 10m のような直近の期間を読む。
+// end が true なら日付だけの指定はその日の終わりまでを含める
+func parseFilterTime(s string, end bool) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation(time.DateOnly, s, time.Local); err == nil {
+		if end {
+			t = t.AddDate(0, 0, 1)
+		}
+		return t, nil
+	}
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(n, 0), nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Now().Add(-d), nil
+}
+
+// active は既定以外の条件が 1 つでも指定されているかを返す
+func (f logFilter) active() bool {
+	f.starredFirst = false
+	return f != logFilter{archived: "0"}
+}
+
+// state は既読・スター・アーカイブ以外の条件がなければ、それだけを保存先に任せる絞り込みにして返す
+func (f logFilter) state() (stateFilter, bool) {
+	state := stateFilter{archived: f.archived}
+	if f.unread {
+		state.read = "0"
+	}
+	if f.starred {
+		state.starred = "1"
+	}
+	f.unread, f.starred, f.archived, f.starredFirst = false, false, "", false
+	return state, f == logFilter{}
+}
+
+func (f logFilter) match(entry LogEntry) bool {
+	if f.ip != "" && entry.IP != f.ip {
+		return false
+	}
+	if !f.since.IsZero() && entry.ID < f.since.UnixNano() {
+		return false
+	}
+	if !f.until.IsZero() && entry.ID >= f.until.UnixNano() {
+		return false
+	}
+	if f.unread && entry.Read {
+		return false
+	}
+	if f.starred && !entry.Starred {
+		return false
+	}
+	if f.archived != "" && entry.Archived != (f.archived == "1") {
+		return false
+	}
+	if f.tag != "" && !slices.Contains(entry.Tags, f.tag) {
+		return false
+	}
+	if f.token != "" && !strings.EqualFold(entry.Token, f.token) {
+		return false
+	}
+	if f.campaign != "" && correlations.campaignOf(entry.Token) != f.campaign {
+		return false
+	}
+	if f.re != nil && !f.re.MatchString(entry.RawRequest) {
+		return false
+	}
+	if f.re == nil && f.query != "" && !strings.Contains(entry.RawRequest, f.query) {
+		return false
+	}
+	if f.method == "" && f.host == "" && f.path == "" {
+		return true
+	}
+	// 項目を持たない古いエントリはここで取り出す
+	entry = withRequestFields(entry)
+	if entry.Method == "" {
+		return false
+	}
+	if f.method != "" && !strings.EqualFold(entry.Method, f.method) {
+		return false
+	}
+	if f.host != "" && !strings.EqualFold(hostWithoutPort(entry.Host), f.host) {
+		return false
+	}
+	return strings.HasPrefix(entry.Path, f.path)
+}
+
+// hostWithoutPort は Host ヘッダーの値からポートを除く
+func hostWithoutPort(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		return h
+	}
+	return host
+}
+
+// handleAPILogs は GET /api/logs?ip=&method=&host=&path=&tag=&token=&campaign=&unread=&starred=&archived=&from=&to=&q=&regex=&limit=&offset= で新しい順に JSON を返す
+func handleAPILogs(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseLogFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+	if limit <= 0 {
+		limit = apiDefaultLimit
+	}
+	limit, offset = min(limit, apiMaxLimit), max(offset, 0)
+
+	logs, total, err := queryLogs(filter, offset, limit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, struct {
+		Total  int        `json:"total"`
+		Offset int        `json:"offset"`
+		Limit  int        `json:"limit"`
+		Logs   []LogEntry `json:"logs"`
+	}{total, offset, limit, logs})
+}
+
+// queryLogs は filter に合うエントリを新しい順に並べ、offset 件目から最大 limit 件と全体の件数を返す。
+// limit が負なら全件。HTTP の API と gRPC で共通。
+// 状態だけの絞り込みなら件数とそのページを保存先に問い合わせ、ほかの条件があれば一度だけ辿って必要な分だけ残す
+func queryLogs(filter logFilter, offset, limit int) ([]LogEntry, int, error) {
+	offset = max(offset, 0)
+	if state, ok := filter.state(); ok {
+		logs, total, err := queryState(state, filter.starredFirst, offset, limit)
+		if logs == nil {
+			logs = []LogEntry{}
+		}
+		return logs, total, err
+	}
+
+	// keep は先頭から残す件数。スター付きを先に並べるときはそれぞれ keep 件まであれば足りる
+	keep := -1
+	if limit >= 0 {
+		keep = offset + min(limit, math.MaxInt-offset)
+	}
+	var starred, rest []LogEntry
+	total := 0
+	err := store.Each(func(entry LogEntry) bool {
+		if !filter.match(entry) {
+			return true
+		}
+		total++
+		if filter.starredFirst && entry.Starred {
+			if keep < 0 || len(starred) < keep {
+				starred = append(starred, entry)
+			}
+		} else if keep < 0 || len(rest) < keep {
+			rest = append(rest, entry)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	logs := append(append([]LogEntry{}, starred...), rest...)
+	offset = min(offset, len(logs))
+	end := len(logs)
+	if limit >= 0 && limit < end-offset {
+		end = offset + limit
+	}
+	return logs[offset:end], total, nil
+}
+
+// queryState は queryLogs の状態だけの場合。スター付きを先に並べるときは、スター付きとそれ以外を別々に数えてつなぐ
+func queryState(f stateFilter, starredFirst bool, offset, limit int) ([]LogEntry, int, error) {
+	total, err := store.CountState(f)
+	if err != nil || limit == 0 {
+		return nil, total, err
+	}
+	limit = max(limit, 0)
+	if !starredFirst || f.starred != "" {
+		logs, err := store.PageState(f, offset, limit)
+		return logs, total, err
+	}
+
+	starred, rest := f, f
+	starred.starred, rest.starred = "1", "0"
+	n, err := store.CountState(starred)
+	if err != nil {
+		return nil, total, err
+	}
+	var logs []LogEntry
+	if offset < n {
+		if logs, err = store.PageState(starred, offset, limit); err != nil {
+			return nil, total, err
+		}
+		if limit > 0 && len(logs) >= limit {
+			return logs, total, nil
+		}
+	}
+	more, err := store.PageState(rest, max(offset-n, 0), limit-len(logs))
+	return append(logs, more...), total, err
+}
+
+// handleAPILog は GET /api/logs/{id} で 1 件を返す
+func handleAPILog(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+	entry, ok, err := store.Get(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	writeJSON(w, entry)
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeJSONFile は v を path に書く。途中で落ちても壊れたファイルが残らないよう、一時ファイルに書いてから置き換える。
+// path が空ならメモリだけに持つ設定なので何もしない
+func writeJSONFile(path string, v any) error {
+	if path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
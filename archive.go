@@ -0,0 +1,263 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// spillArchive はメモリの上限から溢れたエントリを gzip 圧縮した JSONL に書き出す。
+// ファイルは起動ごと・日ごとに分け、書き込みのたびに Flush するので途中でも読み出せる
+type spillArchive struct {
+	mu      sync.Mutex
+	dir     string
+	started string
+	day     string
+	file    *os.File
+	gz      *gzip.Writer
+	cipher  *entryCipher // -encrypt-key 指定時のみ
+	// index はファイルごとの件数。page で全ファイルを展開しないために持つ
+	index map[string]archiveFile
+}
+
+// archiveFile はアーカイブのファイル 1 つの件数と一番新しいエントリの ID。
+// size は数えたときのファイルサイズで、変わっていれば数え直す
+type archiveFile struct {
+	name   string
+	count  int
+	newest int64
+	size   int64
+}
+
+var archive *spillArchive
+
+func newSpillArchive(dir string) (*spillArchive, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &spillArchive{dir: dir, started: time.Now().Format("150405"), index: map[string]archiveFile{}}, nil
+}
+
+// spill は memoryStore から呼ばれ、押し出されたエントリを追記する
+func (a *spillArchive) spill(logs []LogEntry) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if err := a.rotate(); err != nil {
+		fmt.Printf("Archive Error: %v\n", err)
+		return
+	}
+	info := a.index[a.file.Name()]
+	for _, entry := range logs {
+		if a.cipher != nil {
+			entry = a.cipher.seal(entry)
+		}
+		data, err := json.Marshal(entry)
+		if err != nil {
+			continue
+		}
+		a.gz.Write(append(data, '\n'))
+		info.count++
+		info.newest = max(info.newest, entry.ID)
+	}
+	if err := a.gz.Flush(); err != nil {
+		fmt.Printf("Archive Error: %v\n", err)
+	}
+	// 書いている最中のファイルは数え直さずに済むよう、書いた分を索引に足しておく
+	if stat, err := a.file.Stat(); err == nil && info.name != "" {
+		info.size = stat.Size()
+		a.index[info.name] = info
+	}
+}
+
+// rotate は日付が変わったら前のファイルを閉じて新しいファイルを開く
+func (a *spillArchive) rotate() error {
+	day := time.Now().Format("20060102")
+	if a.gz != nil && day == a.day {
+		return nil
+	}
+	if a.gz != nil {
+		a.gz.Close()
+		a.file.Close()
+	}
+	name := filepath.Join(a.dir, fmt.Sprintf("archive-%s-%s.jsonl.gz", day, a.started))
+	f, err := os.OpenFile(name, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	a.day, a.file, a.gz = day, f, gzip.NewWriter(f)
+	// 同じ名前のファイルが既にあれば追記になるので、先に数えておく
+	if info, err := countArchiveFile(name); err == nil {
+		a.index[name] = info
+	} else {
+		a.index[name] = archiveFile{name: name}
+	}
+	return nil
+}
+
+// page は新しい順に並べたアーカイブの offset 件目から最大 limit 件と、全体の件数を返す。
+// ファイルを一番新しいエントリの順に並べ、索引の件数でページに掛かるファイルだけを展開する
+func (a *spillArchive) page(offset, limit int) ([]LogEntry, int, error) {
+	// spill は addLog の途中で呼ばれるので、展開している間はロックを持たない。索引を写してから読み、数え直した分を戻す
+	a.mu.Lock()
+	index := maps.Clone(a.index)
+	a.mu.Unlock()
+	files, err := archiveFiles(a.dir, index)
+	if err != nil {
+		return nil, 0, err
+	}
+	a.mu.Lock()
+	a.mergeIndex(files)
+	a.mu.Unlock()
+
+	total := 0
+	for _, f := range files {
+		total += f.count
+	}
+	var logs []LogEntry
+	for _, f := range files {
+		if len(logs) >= limit {
+			break
+		}
+		if offset >= f.count {
+			offset -= f.count
+			continue
+		}
+		entries, err := readArchiveFile(f.name)
+		if err != nil {
+			fmt.Printf("Archive Error: %s: %v\n", f.name, err)
+		}
+		sort.Slice(entries, func(i, j int) bool { return entries[i].ID > entries[j].ID })
+		entries = entries[min(offset, len(entries)):]
+		offset = 0
+		logs = append(logs, entries[:min(limit-len(logs), len(entries))]...)
+	}
+	if a.cipher != nil {
+		for i := range logs {
+			logs[i] = a.cipher.open(logs[i])
+		}
+	}
+	return logs, total, nil
+}
+
+// archiveFiles はディレクトリのアーカイブを index と照らし合わせ、サイズが変わったものだけを数え直して
+// 一番新しいエントリが新しい順に返す
+func archiveFiles(dir string, index map[string]archiveFile) ([]archiveFile, error) {
+	names, err := filepath.Glob(filepath.Join(dir, "archive-*.jsonl.gz"))
+	if err != nil {
+		return nil, err
+	}
+	files := make([]archiveFile, 0, len(names))
+	for _, name := range names {
+		stat, err := os.Stat(name)
+		if err != nil {
+			continue
+		}
+		info, ok := index[name]
+		if !ok || info.size != stat.Size() {
+			if info, err = countArchiveFile(name); err != nil {
+				fmt.Printf("Archive Error: %s: %v\n", name, err)
+			}
+		}
+		files = append(files, info)
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].newest > files[j].newest })
+	return files, nil
+}
+
+// mergeIndex は archiveFiles の結果で索引を置き換える。消されたファイルは外し、
+// その間に spill が書き足したもの（サイズが大きいもの）や新しく開いたものは残す
+func (a *spillArchive) mergeIndex(files []archiveFile) {
+	index := make(map[string]archiveFile, len(files)+1)
+	for _, f := range files {
+		if cur, ok := a.index[f.name]; ok && cur.size > f.size {
+			f = cur
+		}
+		index[f.name] = f
+	}
+	if a.file != nil {
+		if cur, ok := a.index[a.file.Name()]; ok {
+			if _, seen := index[cur.name]; !seen {
+				index[cur.name] = cur
+			}
+		}
+	}
+	a.index = index
+}
+
+// countArchiveFile はファイルを一度だけ展開して索引の項目を作る
+func countArchiveFile(name string) (archiveFile, error) {
+	stat, err := os.Stat(name)
+	if err != nil {
+		return archiveFile{name: name}, err
+	}
+	entries, err := readArchiveFile(name)
+	info := archiveFile{name: name, count: len(entries), size: stat.Size()}
+	for _, entry := range entries {
+		info.newest = max(info.newest, entry.ID)
+	}
+	return info, err
+}
+
+// readArchiveFile はファイルを読めるところまで読む。書き込み中のファイルは gzip の末尾がないため、
+// io.ErrUnexpectedEOF は終端として扱う
+func readArchiveFile(name string) ([]LogEntry, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, err
+	}
+
+	var logs []LogEntry
+	r := bufio.NewReader(gz)
+	for {
+		line, err := r.ReadBytes('\n')
+		var entry LogEntry
+		if len(line) > 0 && json.Unmarshal(line, &entry) == nil {
+			logs = append(logs, entry)
+		}
+		if err == io.EOF || errors.Is(err, io.ErrUnexpectedEOF) {
+			return logs, nil
+		}
+		if err != nil {
+			return logs, err
+		}
+	}
+}
+
+// handleArchive は /api/archive?page=1&size=50 でアーカイブをページ単位の JSON で返す
+func handleArchive(w http.ResponseWriter, r *http.Request) {
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	size, _ := strconv.Atoi(r.URL.Query().Get("size"))
+	page = max(page, 1)
+	if size <= 0 || size > 500 {
+		size = 50
+	}
+
+	logs, total, err := archive.page((page-1)*size, size)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		Page  int        `json:"page"`
+		Size  int        `json:"size"`
+		Total int        `json:"total"`
+		Logs  []LogEntry `json:"logs"`
+	}{page, size, total, logs})
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// adminAuth は -admin-user と -admin-pass / -admin-pass-hash の資格情報。user が空なら認証しない
+var adminAuth struct {
+	user     string
+	password string
+	hash     []byte // bcrypt
+}
+
+// isAdminPath は管理画面・API・メトリクスとログインのパスかを返す。それ以外は誰でも記録される側。
+// -k8s では /api/v1 以下は Kubernetes API のエミュレーターが受けて記録する
+func isAdminPath(path string) bool {
+	if k8sEnabled && (path == "/api/v1" || strings.HasPrefix(path, "/api/v1/")) {
+		return false
+	}
+	return path == "/admin" || path == "/metrics" || loginPath(path) ||
+		strings.HasPrefix(path, "/admin/") || strings.HasPrefix(path, "/api/")
+}
+
+// checkAdminPassword は user と password が設定と一致するかを定数時間で比べる
+func checkAdminPassword(user, password string) bool {
+	userOK := subtle.ConstantTimeCompare([]byte(user), []byte(adminAuth.user)) == 1
+	if adminAuth.hash != nil {
+		return bcrypt.CompareHashAndPassword(adminAuth.hash, []byte(password)) == nil && userOK
+	}
+	return subtle.ConstantTimeCompare([]byte(password), []byte(adminAuth.password)) == 1 && userOK
+}
+
+// tokenPath は API トークンでも入れるパスかを返す。/admin は人間用の資格情報だけ
+func tokenPath(path string) bool {
+	return path == "/metrics" || strings.HasPrefix(path, "/api/")
+}
+
+// authEnabled は -admin-user か -user-file のユーザーがあれば true
+func authEnabled() bool {
+	return adminAuth.user != "" || !adminUsers.empty()
+}
+
+// authenticate は basic 認証の資格情報を確かめてロールを返す。-admin-user は常に admin
+func authenticate(user, password string) role {
+	if adminAuth.user != "" && checkAdminPassword(user, password) {
+		return roleAdmin
+	}
+	return adminUsers.check(user, password)
+}
+
+// adminAuthorized は管理用のパスへのリクエストをセッション・basic 認証・API トークンの順に認証し、ロールが足りるかを確かめる。
+// ブラウザで管理画面を開いたときはログインフォームへ送る。
+// 通らなければ 401 か 403 を返して false。通れば認証した相手をコンテキストに入れたリクエストを返す
+func adminAuthorized(w http.ResponseWriter, r *http.Request) (*http.Request, bool) {
+	if !authEnabled() || loginPath(r.URL.Path) {
+		return r, true
+	}
+	p, ok := sessionPrincipal(r)
+	if !ok {
+		p = credentialPrincipal(r)
+	}
+	if p.Role == roleNone && wantsLoginPage(r) {
+		http.Redirect(w, r, "/login?next="+url.QueryEscape(r.URL.RequestURI()), http.StatusSeeOther)
+		return r, false
+	}
+	if p.Role == roleNone {
+		w.Header().Set("WWW-Authenticate", `Basic realm="SSRF Monitor", charset="UTF-8"`)
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return r, false
+	}
+	if p.Role < requiredRole(r) {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return r, false
+	}
+	return withPrincipal(r, p), true
+}
+
+// credentialPrincipal は basic 認証か API トークンで認証する。どちらも通らなければ Role が roleNone
+func credentialPrincipal(r *http.Request) principal {
+	if user, password, ok := r.BasicAuth(); ok {
+		return principal{Name: user, Role: authenticate(user, password)}
+	}
+	if token := requestToken(r); token != "" && tokenPath(r.URL.Path) {
+		if t, ok := apiTokens.check(token); ok {
+			return principal{Name: "token:" + t.Name, Role: t.role()}
+		}
+	}
+	return principal{}
+}
+
+// printPasswordHash は標準入力の 1 行目を bcrypt でハッシュして表示する
+func printPasswordHash() {
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(strings.TrimRight(line, "\r\n")), bcrypt.DefaultCost)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+	fmt.Println(string(hash))
+}
+
+// grpcAuthorized は gRPC の authorization メタデータ（Basic か Bearer の API トークン）を確かめる。
+// ClearLogs は operator 以上、それ以外は viewer 以上
+func grpcAuthorized(ctx context.Context, method string) error {
+	if !authEnabled() {
+		return nil
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	got := roleNone
+	for _, v := range md.Get("authorization") {
+		scheme, encoded, _ := strings.Cut(v, " ")
+		if strings.EqualFold(scheme, "Bearer") {
+			if t, ok := apiTokens.check(strings.TrimSpace(encoded)); ok {
+				got = max(got, t.role())
+			}
+			continue
+		}
+		if !strings.EqualFold(scheme, "Basic") {
+			continue
+		}
+		decoded, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			continue
+		}
+		if user, password, ok := strings.Cut(string(decoded), ":"); ok {
+			got = max(got, authenticate(user, password))
+		}
+	}
+	if got == roleNone {
+		return status.Error(codes.Unauthenticated, "unauthorized")
+	}
+	need := roleViewer
+	if strings.HasSuffix(method, "/ClearLogs") {
+		need = roleOperator
+	}
+	if got < need {
+		return status.Error(codes.PermissionDenied, "forbidden")
+	}
+	return nil
+}
+
+func grpcUnaryAuth(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if err := grpcAddrAllowed(ctx); err != nil {
+		return nil, err
+	}
+	if err := grpcAuthorized(ctx, info.FullMethod); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func grpcStreamAuth(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := grpcAddrAllowed(ss.Context()); err != nil {
+		return err
+	}
+	if err := grpcAuthorized(ss.Context(), info.FullMethod); err != nil {
+		return err
+	}
+	return handler(srv, ss)
+}
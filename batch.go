@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	batchSize        = 100
+	batchInterval    = 5 * time.Second
+	batchMaxPending  = 10000
+	batchMaxAttempts = 4
+)
+
+// batchSink は新しいエントリを溜めておき、batchSize 件か batchInterval ごとに send にまとめて渡す。
+// send が失敗したバッチは先頭に戻して次回に回す
+type batchSink struct {
+	mu      sync.Mutex
+	pending []LogEntry
+	wake    chan struct{}
+	name    string // エラー表示用
+	send    func([]LogEntry) error
+}
+
+func newBatchSink(name string, send func([]LogEntry) error) *batchSink {
+	return &batchSink{wake: make(chan struct{}, 1), name: name, send: send}
+}
+
+func (b *batchSink) add(entry LogEntry) {
+	b.mu.Lock()
+	if len(b.pending) < batchMaxPending {
+		b.pending = append(b.pending, entry)
+	}
+	full := len(b.pending) >= batchSize
+	b.mu.Unlock()
+	if full {
+		select {
+		case b.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (b *batchSink) run() {
+	ticker := time.NewTicker(batchInterval)
+	for {
+		select {
+		case <-ticker.C:
+		case <-b.wake:
+		}
+		for {
+			b.mu.Lock()
+			n := min(len(b.pending), batchSize)
+			batch := b.pending[:n:n]
+			b.pending = b.pending[n:]
+			b.mu.Unlock()
+			if n == 0 {
+				break
+			}
+			if err := b.send(batch); err != nil {
+				fmt.Printf("%s Error: %v\n", b.name, err)
+				b.mu.Lock()
+				b.pending = append(batch, b.pending...)
+				if len(b.pending) > batchMaxPending {
+					b.pending = b.pending[len(b.pending)-batchMaxPending:]
+				}
+				b.mu.Unlock()
+				break
+			}
+		}
+	}
+}
+
+// withRetry は attempt が retry を返す間、間隔を倍にしながら最大 batchMaxAttempts 回試す
+func withRetry(attempt func() (retry bool, err error)) error {
+	wait := time.Second
+	for i := 1; ; i++ {
+		retry, err := attempt()
+		if err == nil || !retry || i == batchMaxAttempts {
+			return err
+		}
+		time.Sleep(wait)
+		wait *= 2
+	}
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var boltLogsBucket = []byte("logs")
+
+// boltStore は CGO なしで使える bbolt 版の保存先。キーは ID のビッグエンディアン表現
+type boltStore struct {
+	db *bolt.DB
+}
+
+func openBoltStore(path string) (*boltStore, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(boltLogsBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &boltStore{db: db}, nil
+}
+
+func boltKey(id int64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, uint64(id))
+	return key
+}
+
+func (s *boltStore) Append(entry LogEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltLogsBucket).Put(boltKey(entry.ID), data)
+	})
+}
+
+// List はキーの末尾から辿り、新しい順に返す
+func (s *boltStore) List(limit int) ([]LogEntry, error) {
+	return s.Page(0, limit)
+}
+
+func (s *boltStore) Page(offset, limit int) ([]LogEntry, error) {
+	var logs []LogEntry
+	err := s.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(boltLogsBucket).Cursor()
+		k, v := c.Last()
+		for i := 0; k != nil && i < offset; i++ {
+			k, v = c.Prev()
+		}
+		for ; k != nil && (limit <= 0 || len(logs) < limit); k, v = c.Prev() {
+			logs = appendBoltEntry(logs, v)
+		}
+		return nil
+	})
+	return logs, err
+}
+
+// Since は id の次のキーから先頭へ向かって辿る
+func (s *boltStore) Since(id int64, limit int) ([]LogEntry, error) {
+	var logs []LogEntry
+	err := s.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(boltLogsBucket).Cursor()
+		k, v := c.First()
+		if id >= 0 {
+			k, v = c.Seek(boltKey(id + 1))
+		}
+		for ; k != nil && (limit <= 0 || len(logs) < limit); k, v = c.Next() {
+			logs = appendBoltEntry(logs, v)
+		}
+		return nil
+	})
+	return logs, err
+}
+
+func (s *boltStore) Count() (int, error) {
+	var n int
+	err := s.db.View(func(tx *bolt.Tx) error {
+		n = tx.Bucket(boltLogsBucket).Stats().KeyN
+		return nil
+	})
+	return n, err
+}
+
+// Each はキーの末尾から辿る。状態の索引は持たないので、状態の絞り込みも Each で答える
+func (s *boltStore) Each(fn func(LogEntry) bool) error {
+	return s.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(boltLogsBucket).Cursor()
+		for k, v := c.Last(); k != nil; k, v = c.Prev() {
+			var entry LogEntry
+			if err := json.Unmarshal(v, &entry); err != nil {
+				fmt.Printf("DB Error: skipping broken entry: %v\n", err)
+				continue
+			}
+			if !fn(entry) {
+				break
+			}
+		}
+		return nil
+	})
+}
+
+func (s *boltStore) CountState(f stateFilter) (int, error) {
+	return countState(s, f)
+}
+
+func (s *boltStore) PageState(f stateFilter, offset, limit int) ([]LogEntry, error) {
+	return pageState(s, f, offset, limit)
+}
+
+// appendBoltEntry は読めない値を飛ばす
+func appendBoltEntry(logs []LogEntry, v []byte) []LogEntry {
+	var entry LogEntry
+	if err := json.Unmarshal(v, &entry); err != nil {
+		fmt.Printf("DB Error: skipping broken entry: %v\n", err)
+		return logs
+	}
+	return append(logs, entry)
+}
+
+func (s *boltStore) Get(id int64) (LogEntry, bool, error) {
+	var entry LogEntry
+	var found bool
+	err := s.db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket(boltLogsBucket).Get(boltKey(id))
+		if v == nil {
+			return nil
+		}
+		found = true
+		return json.Unmarshal(v, &entry)
+	})
+	return entry, found && err == nil, err
+}
+
+func (s *boltStore) Update(entry LogEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(boltLogsBucket)
+		if b.Get(boltKey(entry.ID)) == nil {
+			return nil
+		}
+		return b.Put(boltKey(entry.ID), data)
+	})
+}
+
+func (s *boltStore) Delete(id int64) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltLogsBucket).Delete(boltKey(id))
+	})
+}
+
+func (s *boltStore) Clear() error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		if err := tx.DeleteBucket(boltLogsBucket); err != nil {
+			return err
+		}
+		_, err := tx.CreateBucket(boltLogsBucket)
+		return err
+	})
+}
+
+// Prune は条件に当てはまるキーを集めてから消す（カーソルで辿りながら消すと位置がずれるため）
+func (s *boltStore) Prune(keep int, before time.Time) (int, error) {
+	var n int
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(boltLogsBucket)
+		var keys [][]byte
+		i := 0
+		c := b.Cursor()
+		for k, _ := c.Last(); k != nil; k, _ = c.Prev() {
+			if pruned(LogEntry{ID: int64(binary.BigEndian.Uint64(k))}, i, keep, before) {
+				keys = append(keys, k)
+			}
+			i++
+		}
+		for _, k := range keys {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+		}
+		n = len(keys)
+		return nil
+	})
+	return n, err
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+)
+
+// burpTimeFormat は Burp の items XML で使われる日時の書式
+const burpTimeFormat = "Mon Jan 02 15:04:05 MST 2006"
+
+type burpItems struct {
+	XMLName     xml.Name   `xml:"items"`
+	BurpVersion string     `xml:"burpVersion,attr"`
+	ExportTime  string     `xml:"exportTime,attr"`
+	Items       []burpItem `xml:"item"`
+}
+
+type burpItem struct {
+	Time           string      `xml:"time"`
+	URL            burpCDATA   `xml:"url"`
+	Host           burpHost    `xml:"host"`
+	Port           string      `xml:"port"`
+	Protocol       string      `xml:"protocol"`
+	Method         burpCDATA   `xml:"method"`
+	Path           burpCDATA   `xml:"path"`
+	Extension      string      `xml:"extension"`
+	Request        burpPayload `xml:"request"`
+	Status         int         `xml:"status"`
+	ResponseLength int         `xml:"responselength"`
+	MimeType       string      `xml:"mimetype"`
+	Response       burpPayload `xml:"response"`
+	Comment        string      `xml:"comment"`
+}
+
+type burpCDATA struct {
+	Text string `xml:",cdata"`
+}
+
+type burpHost struct {
+	IP   string `xml:"ip,attr"`
+	Name string `xml:",chardata"`
+}
+
+type burpPayload struct {
+	Base64 bool   `xml:"base64,attr"`
+	Data   string `xml:",cdata"`
+}
+
+// handleExportBurp は HTTP として読めるエントリを Burp Suite の「Save items」と同じ XML で返す。
+// Burp の Target / Logger にそのまま取り込んでリピーターに送れる
+func handleExportBurp(w http.ResponseWriter, r *http.Request) {
+	logs, err := campaignLogs(r, maxLogs)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	items := burpItems{BurpVersion: "2023.10", ExportTime: time.Now().Format(burpTimeFormat)}
+	for i := len(logs) - 1; i >= 0; i-- {
+		if item, ok := burpFromEntry(logs[i]); ok {
+			items.Items = append(items.Items, item)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="ssrf_logs_`+time.Now().Format("20060102_150405")+`.xml"`)
+	io.WriteString(w, xml.Header)
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	enc.Encode(items)
+}
+
+func burpFromEntry(entry LogEntry) (burpItem, bool) {
+	req := entryRequest(entry)
+	if req == nil {
+		return burpItem{}, false
+	}
+	body, _ := io.ReadAll(req.Body)
+
+	scheme, port := "http", "80"
+	if entry.Protocol == "https" || entry.Protocol == "wss" || entry.TLS != nil {
+		scheme, port = "https", "443"
+	}
+	host := req.Host
+	if h, p, err := net.SplitHostPort(req.Host); err == nil {
+		host, port = h, p
+	}
+	ip := ""
+	if net.ParseIP(host) != nil {
+		ip = host
+	}
+
+	item := burpItem{
+		Time:      time.Unix(0, entry.ID).Format(burpTimeFormat),
+		URL:       burpCDATA{harURL(entry, req)},
+		Host:      burpHost{IP: ip, Name: host},
+		Port:      port,
+		Protocol:  scheme,
+		Method:    burpCDATA{req.Method},
+		Path:      burpCDATA{req.URL.RequestURI()},
+		Extension: "null",
+		Request:   burpPayload{Base64: true, Data: base64.StdEncoding.EncodeToString(http1Request(entry, req, body))},
+		MimeType:  "text",
+		Comment:   fmt.Sprintf("%s from %s", entry.Protocol, entry.IP),
+	}
+	if ext := strings.TrimPrefix(path.Ext(req.URL.Path), "."); ext != "" {
+		item.Extension = ext
+	}
+	if res := entryResponse(entry); res != nil {
+		raw := crlfHeaders(entry.RawResponse)
+		item.Status = res.StatusCode
+		item.ResponseLength = len(raw)
+		item.Response = burpPayload{Base64: true, Data: base64.StdEncoding.EncodeToString(raw)}
+		if strings.Contains(res.Header.Get("Content-Type"), "json") {
+			item.MimeType = "JSON"
+		}
+	}
+	return item, true
+}
+
+// http1Request は HTTP/1.x のダンプはそのまま、HTTP/2 のダンプは HTTP/1.1 形式に組み直して返す
+func http1Request(entry LogEntry, req *http.Request, body []byte) []byte {
+	if req.ProtoMajor != 2 {
+		return []byte(entry.RawRequest)
+	}
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "%s %s HTTP/1.1\r\nHost: %s\r\n", req.Method, req.RequestURI, req.Host)
+	req.Header.Write(&b)
+	b.WriteString("\r\n")
+	b.Write(body)
+	return b.Bytes()
+}
+
+// crlfHeaders は LF 区切りで記録したレスポンスのヘッダー部分を CRLF に直す
+func crlfHeaders(raw string) []byte {
+	head, body, found := strings.Cut(raw, "\n\n")
+	head = strings.ReplaceAll(strings.ReplaceAll(head, "\r\n", "\n"), "\n", "\r\n")
+	if !found {
+		return []byte(head)
+	}
+	return []byte(head + "\r\n\r\n" + body)
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// alertProtocol は監視側が生成したアラートのエントリに付けるプロトコル名
+const alertProtocol = "alert"
+
+// burstDetector は window の間に threshold 件を超えるリクエストが来たらアラートのエントリを記録する。
+// perIP なら送信元ごとに数える。同じ対象では window の間に 1 回しか発火しない
+type burstDetector struct {
+	mu        sync.Mutex
+	threshold int
+	window    time.Duration
+	perIP     bool
+	hits      map[string][]time.Time
+	quietTill map[string]time.Time
+	swept     time.Time
+}
+
+func newBurstDetector(threshold int, window time.Duration, perIP bool) *burstDetector {
+	return &burstDetector{
+		threshold: threshold,
+		window:    window,
+		perIP:     perIP,
+		hits:      map[string][]time.Time{},
+		quietTill: map[string]time.Time{},
+	}
+}
+
+func (d *burstDetector) add(entry LogEntry) {
+	if entry.Protocol == alertProtocol {
+		return
+	}
+	key := ""
+	if d.perIP {
+		key = entry.IP
+	}
+	now := time.Now()
+
+	d.mu.Lock()
+	d.sweep(now)
+	hits := append(d.recent(key, now), now)
+	d.hits[key] = hits
+	fire := len(hits) > d.threshold && now.After(d.quietTill[key])
+	if fire {
+		d.quietTill[key] = now.Add(d.window)
+	}
+	d.mu.Unlock()
+
+	if fire {
+		addLog(d.alert(key, len(hits)))
+	}
+}
+
+// recent は key の window 以内のヒットだけを返す
+func (d *burstDetector) recent(key string, now time.Time) []time.Time {
+	hits := d.hits[key]
+	i := 0
+	for i < len(hits) && now.Sub(hits[i]) > d.window {
+		i++
+	}
+	return hits[i:]
+}
+
+// sweep は送信元ごとの集計が溜まり続けないよう、window ごとに古い送信元を消す
+func (d *burstDetector) sweep(now time.Time) {
+	if now.Sub(d.swept) < d.window {
+		return
+	}
+	d.swept = now
+	for key := range d.hits {
+		if len(d.recent(key, now)) == 0 {
+			delete(d.hits, key)
+		}
+	}
+	for key, till := range d.quietTill {
+		if now.After(till) {
+			delete(d.quietTill, key)
+		}
+	}
+}
+
+func (d *burstDetector) alert(key string, count int) LogEntry {
+	target := "全体"
+	if key != "" {
+		target = key
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "バースト検知: %s の間に %d 件（しきい値 %d）\n", d.window, count, d.threshold)
+	fmt.Fprintf(&b, "対象: %s\n", target)
+	entry := newLogEntry(alertProtocol, key, b.String(), "")
+	entry.Tags = []string{"burst"}
+	return entry
+}
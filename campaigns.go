@@ -0,0 +1,202 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// campaign は案件ごとに相関トークンをまとめる単位。同じサーバーで複数の案件を並行しても証跡が混ざらないようにする
+type campaign struct {
+	ID      string    `json:"id"`
+	Name    string    `json:"name"`
+	Created time.Time `json:"created"`
+	expiry            // 紐づけた相関トークン全ての期限になる
+}
+
+var errCampaignNotFound = errors.New("campaign not found")
+
+// campaignIndex は呼び出し側でロックを取っておく
+func (s *correlationStore) campaignIndex(id string) int {
+	return slices.IndexFunc(s.campaigns, func(c campaign) bool { return c.ID == id })
+}
+
+func (s *correlationStore) createCampaign(name string, exp expiry) (campaign, error) {
+	b := make([]byte, 4)
+	rand.Read(b)
+	c := campaign{ID: hex.EncodeToString(b), Name: name, Created: time.Now(), expiry: exp}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.campaigns = append(s.campaigns, c)
+	return c, s.save()
+}
+
+func (s *correlationStore) listCampaigns() []campaign {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.campaigns)
+}
+
+// updateToken は発行済みの相関トークンを fn で書き換えて保存する。紐づけ先のキャンペーンがなければ変えない
+func (s *correlationStore) updateToken(token string, fn func(*correlationToken)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := slices.IndexFunc(s.tokens, func(t correlationToken) bool { return t.Token == token })
+	if i < 0 {
+		return errTokenNotFound
+	}
+	t := s.tokens[i]
+	fn(&t)
+	if t.Campaign != "" && s.campaignIndex(t.Campaign) < 0 {
+		return errCampaignNotFound
+	}
+	s.tokens[i] = t
+	return s.save()
+}
+
+// updateCampaign はキャンペーンを fn で書き換えて保存する
+func (s *correlationStore) updateCampaign(id string, fn func(*campaign)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.campaignIndex(id)
+	if i < 0 {
+		return errCampaignNotFound
+	}
+	c := s.campaigns[i]
+	fn(&c)
+	s.campaigns[i] = c
+	return s.save()
+}
+
+var errTokenNotFound = errors.New("token not found")
+
+// campaignOf は相関 ID が紐づくキャンペーンの ID。発行していない ID や紐づけていないトークンは空
+func (s *correlationStore) campaignOf(token string) string {
+	if token == "" {
+		return ""
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, t := range s.tokens {
+		if t.Token == token {
+			return t.Campaign
+		}
+	}
+	return ""
+}
+
+// campaignLogs はエクスポートや集計用に保存されているエントリを新しい順に最大 limit 件返す（0 以下なら全件）。
+// ?campaign= があればそのキャンペーンの分だけを、絞り込んでから limit 件まで集める
+func campaignLogs(r *http.Request, limit int) ([]LogEntry, error) {
+	id := r.URL.Query().Get("campaign")
+	if id == "" {
+		return store.List(limit)
+	}
+	filter := logFilter{campaign: id}
+	var logs []LogEntry
+	err := store.Each(func(entry LogEntry) bool {
+		if filter.match(entry) {
+			logs = append(logs, entry)
+		}
+		return limit <= 0 || len(logs) < limit
+	})
+	return logs, err
+}
+
+// handleCampaigns は GET /api/campaigns で一覧を、POST /api/campaigns で {"name": "...", "expires": "720h", "purge": false} のキャンペーンを作る
+func handleCampaigns(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodGet {
+		writeJSON(w, correlations.listCampaigns())
+		return
+	}
+	var body struct {
+		Name string `json:"name"`
+		expiryBody
+	}
+	if err := json.NewDecoder(io.LimitReader(r.Body, 4096)).Decode(&body); err != nil || strings.TrimSpace(body.Name) == "" {
+		http.Error(w, "name is required", http.StatusBadRequest)
+		return
+	}
+	change, ok := body.changeOrError(w)
+	if !ok {
+		return
+	}
+	var exp expiry
+	change(&exp)
+	c, err := correlations.createCampaign(strings.TrimSpace(body.Name), exp)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	writeJSON(w, c)
+}
+
+// handleUpdateToken は PUT /api/tokens/{token} で {"campaign": "<id>"}（空なら外す）、{"expires": "24h"}（空なら期限なし）、
+// {"purge": true} のうち指定した項目を変える
+func handleUpdateToken(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Campaign *string `json:"campaign"`
+		expiryBody
+	}
+	if err := json.NewDecoder(io.LimitReader(r.Body, 4096)).Decode(&body); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	change, ok := body.changeOrError(w)
+	if !ok {
+		return
+	}
+	err := correlations.updateToken(r.PathValue("token"), func(t *correlationToken) {
+		if body.Campaign != nil {
+			t.Campaign = *body.Campaign
+		}
+		change(&t.expiry)
+	})
+	switch {
+	case errors.Is(err, errTokenNotFound):
+		http.NotFound(w, r)
+	case errors.Is(err, errCampaignNotFound):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	default:
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// handleUpdateCampaign は PUT /api/campaigns/{id} で {"name"}、{"expires"}、{"purge"} のうち指定した項目を変える
+func handleUpdateCampaign(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Name *string `json:"name"`
+		expiryBody
+	}
+	if err := json.NewDecoder(io.LimitReader(r.Body, 4096)).Decode(&body); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	change, ok := body.changeOrError(w)
+	if !ok {
+		return
+	}
+	err := correlations.updateCampaign(r.PathValue("id"), func(c *campaign) {
+		if body.Name != nil && strings.TrimSpace(*body.Name) != "" {
+			c.Name = strings.TrimSpace(*body.Name)
+		}
+		change(&c.expiry)
+	})
+	switch {
+	case errors.Is(err, errCampaignNotFound):
+		http.NotFound(w, r)
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	default:
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,240 @@
+// Package client は SSRF Monitor の HTTP API（/api/openapi.json）を Go から使うためのクライアント。
+//
+//	c := client.New("http://monitor.example.com")
+//	logs, err := c.ListLogs(ctx, client.ListOptions{Path: "/token/", Limit: 10})
+package client
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LogEntry は記録された 1 件。ID は受信時刻の UNIX ナノ秒
+type LogEntry struct {
+	ID           int64    `json:"id"`
+	Timestamp    string   `json:"timestamp"`
+	FilenameTS   string   `json:"filename_ts"`
+	Protocol     string   `json:"protocol"`
+	ProtoVersion string   `json:"proto_version,omitempty"`
+	ParentID     int64    `json:"parent_id,omitempty"`
+	IP           string   `json:"ip"`
+	Method       string   `json:"method,omitempty"` // HTTP 系のエントリのみ
+	Host         string   `json:"host,omitempty"`
+	Path         string   `json:"path,omitempty"`
+	RawRequest   string   `json:"raw_request"`
+	RawResponse  string   `json:"raw_response"`
+	TLS          *TLSInfo `json:"tls,omitempty"`
+	Tags         []string `json:"tags,omitempty"`
+	Note         string   `json:"note,omitempty"`
+	Read         bool     `json:"read,omitempty"`
+	Starred      bool     `json:"starred,omitempty"`
+	Archived     bool     `json:"archived,omitempty"`
+	Token        string   `json:"token,omitempty"` // 相関トークン
+}
+
+// TLSInfo は HTTPS で受けた場合のハンドシェイク情報
+type TLSInfo struct {
+	SNI            string   `json:"sni"`
+	Version        string   `json:"version"`
+	CipherSuite    string   `json:"cipher_suite"`
+	ALPN           string   `json:"alpn"`
+	OfferedCiphers []string `json:"offered_ciphers"`
+	OfferedALPN    []string `json:"offered_alpn"`
+	JA3            string   `json:"ja3"`
+	JA3Hash        string   `json:"ja3_hash"`
+	JA3S           string   `json:"ja3s"`
+	JA3SHash       string   `json:"ja3s_hash"`
+}
+
+// ListOptions は ListLogs の絞り込み条件。ゼロ値の項目は送らない
+type ListOptions struct {
+	IP     string
+	Path   string // HTTP のパスの前方一致
+	Method string
+	Host   string // ポートを除いた Host の完全一致
+	Since  time.Time
+	Until  time.Time
+	Tag    string
+	Token  string // 相関トークン
+	// Campaign はそのキャンペーンに紐づけた相関トークン宛てのものだけ
+	Campaign string
+	Unread   bool // 未読のものだけ
+	// Starred はスター付きのものだけ
+	Starred bool
+	// Archived は "1" ならアーカイブしたものだけ、"all" なら区別しない。空ならアーカイブしたものを除く
+	Archived string
+	Query    string // RawRequest の部分一致
+	Regex    bool   // Query を正規表現として使う
+	Limit    int
+	Offset   int
+}
+
+// LogList は ListLogs の結果
+type LogList struct {
+	Total  int        `json:"total"`
+	Offset int        `json:"offset"`
+	Limit  int        `json:"limit"`
+	Logs   []LogEntry `json:"logs"`
+}
+
+// Error は 2xx 以外が返ってきたときのエラー
+type Error struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("ssrf monitor: %d: %s", e.StatusCode, e.Message)
+}
+
+// Client は 1 台の SSRF Monitor に接続する
+type Client struct {
+	BaseURL    string
+	HTTPClient *http.Client
+	// Header は全てのリクエストに付ける（認証ヘッダーなど）
+	Header http.Header
+}
+
+// New は baseURL（例 http://localhost:3001）のクライアントを作る
+func New(baseURL string) *Client {
+	return &Client{BaseURL: strings.TrimSuffix(baseURL, "/"), HTTPClient: http.DefaultClient, Header: http.Header{}}
+}
+
+// SetBasicAuth はサーバーを -admin-user で起動している場合の資格情報を設定する
+func (c *Client) SetBasicAuth(user, password string) {
+	c.Header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(user+":"+password)))
+}
+
+// SetToken は /admin/tokens で発行した API トークンを設定する
+func (c *Client) SetToken(token string) {
+	c.Header.Set("Authorization", "Bearer "+token)
+}
+
+// ListLogs は GET /api/logs で条件に合うエントリを新しい順に返す
+func (c *Client) ListLogs(ctx context.Context, opts ListOptions) (*LogList, error) {
+	q := url.Values{}
+	if opts.IP != "" {
+		q.Set("ip", opts.IP)
+	}
+	if opts.Path != "" {
+		q.Set("path", opts.Path)
+	}
+	if opts.Method != "" {
+		q.Set("method", opts.Method)
+	}
+	if opts.Host != "" {
+		q.Set("host", opts.Host)
+	}
+	if !opts.Since.IsZero() {
+		q.Set("from", opts.Since.Format(time.RFC3339))
+	}
+	if !opts.Until.IsZero() {
+		q.Set("to", opts.Until.Format(time.RFC3339))
+	}
+	if opts.Tag != "" {
+		q.Set("tag", opts.Tag)
+	}
+	if opts.Token != "" {
+		q.Set("token", opts.Token)
+	}
+	if opts.Campaign != "" {
+		q.Set("campaign", opts.Campaign)
+	}
+	if opts.Unread {
+		q.Set("unread", "1")
+	}
+	if opts.Starred {
+		q.Set("starred", "1")
+	}
+	if opts.Archived != "" {
+		q.Set("archived", opts.Archived)
+	}
+	if opts.Query != "" {
+		q.Set("q", opts.Query)
+		if opts.Regex {
+			q.Set("regex", "1")
+		}
+	}
+	if opts.Limit > 0 {
+		q.Set("limit", strconv.Itoa(opts.Limit))
+	}
+	if opts.Offset > 0 {
+		q.Set("offset", strconv.Itoa(opts.Offset))
+	}
+	var list LogList
+	if err := c.getJSON(ctx, "/api/logs?"+q.Encode(), &list); err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+// GetLog は GET /api/logs/{id} で 1 件を返す
+func (c *Client) GetLog(ctx context.Context, id int64) (*LogEntry, error) {
+	var entry LogEntry
+	if err := c.getJSON(ctx, "/api/logs/"+strconv.FormatInt(id, 10), &entry); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// Export は GET /api/logs/{id}/export で format（txt / http / curl）の文字列を返す
+func (c *Client) Export(ctx context.Context, id int64, format string) (string, error) {
+	resp, err := c.get(ctx, "/api/logs/"+strconv.FormatInt(id, 10)+"/export?format="+url.QueryEscape(format))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	return string(data), err
+}
+
+// Poll は GET /api/poll で sinceID より新しいエントリを古い順に返す。なければ timeout まで待つ。
+// 戻り値の next を次の sinceID に渡す
+func (c *Client) Poll(ctx context.Context, sinceID int64, timeout time.Duration) (logs []LogEntry, next int64, err error) {
+	var result struct {
+		Logs        []LogEntry `json:"logs"`
+		NextSinceID int64      `json:"next_since_id"`
+	}
+	path := fmt.Sprintf("/api/poll?since_id=%d&timeout=%d", sinceID, int(timeout/time.Second))
+	if err := c.getJSON(ctx, path, &result); err != nil {
+		return nil, sinceID, err
+	}
+	return result.Logs, result.NextSinceID, nil
+}
+
+func (c *Client) get(ctx context.Context, path string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.BaseURL+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range c.Header {
+		req.Header[name] = values
+	}
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		defer resp.Body.Close()
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, &Error{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(msg))}
+	}
+	return resp, nil
+}
+
+func (c *Client) getJSON(ctx context.Context, path string, v any) error {
+	resp, err := c.get(ctx, path)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return json.NewDecoder(resp.Body).Decode(v)
+}
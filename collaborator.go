@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/base64"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// collaboratorCursors は biid ごとに最後に返したエントリの ID。Collaborator と同じく一度返したやり取りは次の poll では返さない
+var collaboratorCursors = struct {
+	sync.Mutex
+	last map[string]int64
+}{last: map[string]int64{}}
+
+// collaboratorResponse は Burp Collaborator の polling で返す 1 件のやり取り
+type collaboratorResponse struct {
+	Protocol          string            `json:"protocol"`
+	OpCode            string            `json:"opCode"`
+	InteractionString string            `json:"interactionString"`
+	ClientIP          string            `json:"clientIp"`
+	Time              string            `json:"time"` // UNIX ミリ秒
+	Data              map[string]string `json:"data"`
+}
+
+func collaboratorFromEntry(entry LogEntry) collaboratorResponse {
+	res := collaboratorResponse{
+		Protocol:          entry.Protocol,
+		OpCode:            "0",
+		InteractionString: entry.Token,
+		ClientIP:          entry.IP,
+		Time:              strconv.FormatInt(entry.ID/1e6, 10),
+	}
+	raw := base64.StdEncoding.EncodeToString([]byte(entry.RawRequest))
+	switch entry.Protocol {
+	case "http", "https":
+		res.Data = map[string]string{"request": raw, "response": base64.StdEncoding.EncodeToString([]byte(entry.RawResponse))}
+	case "dns":
+		qname, qtype := "", ""
+		for _, line := range strings.Split(entry.RawRequest, "\n") {
+			if v, ok := strings.CutPrefix(line, "QNAME: "); ok {
+				qname = strings.TrimSuffix(v, ".")
+			} else if v, ok := strings.CutPrefix(line, "QTYPE: "); ok {
+				qtype = v
+			}
+		}
+		res.Data = map[string]string{"subDomain": qname, "type": qtype, "rawRequest": raw}
+	case "smtp":
+		res.Data = map[string]string{"conversation": raw}
+	default:
+		res.Data = map[string]string{"rawRequest": raw}
+	}
+	return res
+}
+
+// handleCollaboratorPoll は GET /burpresults?biid=<biid> で、前回の poll 以降に biid の相関トークン宛てに届いたやり取りを
+// Collaborator の polling と同じ形で返す。トークンはペイロードに出て誰にでも見えるので、/api/tokens で発行したときの
+// 秘密の biid でしか引けない
+func handleCollaboratorPoll(w http.ResponseWriter, r *http.Request) {
+	biid := r.URL.Query().Get("biid")
+	token, ok := correlations.tokenByBIID(biid)
+	if biid == "" || !ok {
+		writeJSON(w, struct{}{})
+		return
+	}
+
+	collaboratorCursors.Lock()
+	defer collaboratorCursors.Unlock()
+	// 初回はトークンを発行した時点から読む。それより前にトークン宛てのものはない
+	last, ok := collaboratorCursors.last[biid]
+	if !ok && !token.Created.IsZero() {
+		last = token.Created.UnixNano() - 1
+	}
+	logs, err := store.Since(last, 0)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	var responses []collaboratorResponse
+	for _, entry := range logs {
+		if entry.Token == token.Token {
+			responses = append(responses, collaboratorFromEntry(entry))
+		}
+	}
+	if len(logs) > 0 {
+		collaboratorCursors.last[biid] = logs[len(logs)-1].ID
+	}
+	if len(responses) == 0 {
+		writeJSON(w, struct{}{})
+		return
+	}
+	writeJSON(w, map[string]any{"responses": responses})
+}
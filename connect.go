@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"sync"
+	"time"
+)
+
+// connectSink は CONNECT のトンネル先。空ならトンネルせずに 502 を返す
+var connectSink string
+
+// captureBuffer は maxCaptureSize までの書き込みだけを保持する
+type captureBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (c *captureBuffer) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if room := maxCaptureSize - c.buf.Len(); room > 0 {
+		c.buf.Write(p[:min(len(p), room)])
+	}
+	return len(p), nil
+}
+
+func (c *captureBuffer) Bytes() []byte {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.buf.Bytes()
+}
+
+// handleConnect は CONNECT の接続先とクライアントを記録し、sink が設定されていればトンネルして中身も記録する
+func handleConnect(w http.ResponseWriter, r *http.Request) {
+	clientIP := requestClientIP(r)
+	requestDump, _ := httputil.DumpRequest(r, false)
+
+	if connectSink == "" {
+		rawResponse := "HTTP/1.1 502 Bad Gateway\n\nTunnel disabled"
+		addLog(newLogEntry("connect", clientIP, string(requestDump), rawResponse))
+		http.Error(w, "Tunnel disabled", http.StatusBadGateway)
+		return
+	}
+
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "Hijacking not supported", http.StatusInternalServerError)
+		return
+	}
+	sink, err := net.DialTimeout("tcp", connectSink, 10*time.Second)
+	if err != nil {
+		rawResponse := fmt.Sprintf("HTTP/1.1 502 Bad Gateway\n\n%v", err)
+		addLog(newLogEntry("connect", clientIP, string(requestDump), rawResponse))
+		http.Error(w, "Bad Gateway", http.StatusBadGateway)
+		return
+	}
+	defer sink.Close()
+
+	conn, rw, err := hj.Hijack()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	established := "HTTP/1.1 200 Connection Established\r\n\r\n"
+	conn.Write([]byte(established))
+	parent := newLogEntry("connect", clientIP, string(requestDump), established)
+	parent = addLog(parent)
+
+	deadline := time.Now().Add(connTimeout)
+	conn.SetDeadline(deadline)
+	sink.SetDeadline(deadline)
+
+	var upstream, downstream captureBuffer
+	done := make(chan struct{})
+	go func() {
+		io.Copy(io.MultiWriter(conn, &downstream), sink)
+		conn.Close()
+		close(done)
+	}()
+	io.Copy(io.MultiWriter(sink, &upstream), rw.Reader)
+	sink.Close()
+	<-done
+
+	entry := newLogEntry("connect", clientIP,
+		fmt.Sprintf("Tunnel %s -> %s\n\n%s", r.Host, connectSink, dumpBytes(upstream.Bytes())),
+		dumpBytes(downstream.Bytes()))
+	entry.ParentID = parent.ID
+	addLog(entry)
+}
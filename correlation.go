@@ -0,0 +1,235 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// correlationEncoding は DNS のラベルとして大文字小文字を区別されても壊れない小文字の base32
+var correlationEncoding = base32.NewEncoding("abcdefghijklmnopqrstuvwxyz234567").WithPadding(base32.NoPadding)
+
+// correlationToken は注入箇所ごとに発行する相関トークン。ペイロード URL に埋め込み、届いたリクエストと結びつける
+type correlationToken struct {
+	Token    string    `json:"token"`
+	Label    string    `json:"label,omitempty"`    // どこに注入したかのメモ
+	Campaign string    `json:"campaign,omitempty"` // 紐づけたキャンペーンの ID
+	BIID     string    `json:"biid,omitempty"`     // /burpresults の poll に使う秘密の ID。トークンはペイロードに出るので別にする
+	Created  time.Time `json:"created"`
+	expiry
+}
+
+// correlationStore は発行した相関トークンとキャンペーンの一覧。path が空ならメモリだけに持つ
+type correlationStore struct {
+	mu        sync.Mutex
+	path      string
+	tokens    []correlationToken
+	campaigns []campaign
+}
+
+// correlationFile は -correlation-file の中身
+type correlationFile struct {
+	Tokens    []correlationToken `json:"tokens"`
+	Campaigns []campaign         `json:"campaigns"`
+}
+
+var correlations = &correlationStore{}
+
+// load は -correlation-file を読む。ファイルがなければ空から始める
+func (s *correlationStore) load(path string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.path = path
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	// キャンペーンを入れる前はトークンの配列だけだった
+	if strings.HasPrefix(strings.TrimSpace(string(data)), "[") {
+		if err := json.Unmarshal(data, &s.tokens); err != nil {
+			return err
+		}
+		return s.fillBIIDs()
+	}
+	var file correlationFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return err
+	}
+	s.tokens, s.campaigns = file.Tokens, file.Campaigns
+	return s.fillBIIDs()
+}
+
+// fillBIIDs は biid を入れる前に発行したトークンに biid を付けて保存する
+func (s *correlationStore) fillBIIDs() error {
+	filled := false
+	for i := range s.tokens {
+		if s.tokens[i].BIID == "" {
+			s.tokens[i].BIID = newBIID()
+			filled = true
+		}
+	}
+	if !filled {
+		return nil
+	}
+	return s.save()
+}
+
+func newBIID() string {
+	b := make([]byte, 20)
+	rand.Read(b)
+	return correlationEncoding.EncodeToString(b)
+}
+
+// tokenByBIID は biid に対応する相関トークンを返す
+func (s *correlationStore) tokenByBIID(biid string) (correlationToken, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, t := range s.tokens {
+		if t.BIID != "" && subtle.ConstantTimeCompare([]byte(t.BIID), []byte(biid)) == 1 {
+			return t, true
+		}
+	}
+	return correlationToken{}, false
+}
+
+// save は呼び出し側でロックを取っておく
+func (s *correlationStore) save() error {
+	return writeJSONFile(s.path, correlationFile{s.tokens, s.campaigns})
+}
+
+func (s *correlationStore) create(label, campaignID string, exp expiry) (correlationToken, error) {
+	b := make([]byte, 10)
+	rand.Read(b)
+	token := correlationToken{Token: correlationEncoding.EncodeToString(b), Label: label, Campaign: campaignID, BIID: newBIID(), Created: time.Now(), expiry: exp}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if campaignID != "" && s.campaignIndex(campaignID) < 0 {
+		return correlationToken{}, errCampaignNotFound
+	}
+	s.tokens = append(s.tokens, token)
+	return token, s.save()
+}
+
+func (s *correlationStore) list() []correlationToken {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.tokens)
+}
+
+func (s *correlationStore) has(token string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.ContainsFunc(s.tokens, func(t correlationToken) bool { return t.Token == token })
+}
+
+// payloadURLs はトークンを埋め込んだ貼り付け用の URL とホスト名
+func payloadURLs(token string) map[string]string {
+	return map[string]string{
+		"subdomain": "http://" + token + "." + serverDomain + "/",
+		"path":      "http://" + serverDomain + "/t/" + token,
+		"dns":       token + "." + domainHost(),
+	}
+}
+
+// subdomainLabel は name が <label>.serverDomain の形ならドメインのすぐ左のラベルを返す
+func subdomainLabel(name string) string {
+	name = strings.ToLower(strings.TrimSuffix(hostWithoutPort(name), "."))
+	sub, ok := strings.CutSuffix(name, "."+strings.ToLower(domainHost()))
+	if !ok || sub == "" {
+		return ""
+	}
+	return sub[strings.LastIndex(sub, ".")+1:]
+}
+
+// entryCorrelation はエントリの Host のサブドメイン、/t/<token> のパス、DNS の問い合わせ名から相関トークンの候補を取り出す
+func entryCorrelation(entry LogEntry) []string {
+	var candidates []string
+	if entry.Host != "" {
+		candidates = append(candidates, subdomainLabel(entry.Host))
+	}
+	if rest, ok := strings.CutPrefix(entry.Path, "/t/"); ok {
+		token, _, _ := strings.Cut(rest, "/")
+		candidates = append(candidates, strings.ToLower(token))
+	}
+	if entry.Protocol == "dns" {
+		if qname, ok := strings.CutPrefix(entry.RawRequest, "QNAME: "); ok {
+			qname, _, _ = strings.Cut(qname, "\n")
+			candidates = append(candidates, subdomainLabel(qname))
+		}
+	}
+	return candidates
+}
+
+// withCorrelation はエントリに相関 ID（Token）を付ける。発行済みの相関トークンを優先し、
+// なければ <anything>.serverDomain のサブドメインのラベルなどをそのまま相関 ID にする
+func withCorrelation(entry LogEntry) LogEntry {
+	if entry.Token != "" {
+		return entry
+	}
+	candidates := slices.DeleteFunc(entryCorrelation(entry), func(c string) bool { return c == "" })
+	for _, token := range candidates {
+		if correlations.has(token) {
+			entry.Token = token
+			return entry
+		}
+	}
+	if len(candidates) > 0 {
+		entry.Token = candidates[0]
+	}
+	return entry
+}
+
+// handleCorrelationTokens は GET /api/tokens で一覧を、POST /api/tokens で
+// {"label": "...", "campaign": "<id>", "expires": "72h", "purge": true} の相関トークンを発行し、貼り付け用の URL を返す
+func handleCorrelationTokens(w http.ResponseWriter, r *http.Request) {
+	type tokenWithURLs struct {
+		correlationToken
+		URLs map[string]string `json:"urls"`
+	}
+	if r.Method == http.MethodGet {
+		tokens := []tokenWithURLs{}
+		for _, t := range correlations.list() {
+			tokens = append(tokens, tokenWithURLs{t, payloadURLs(t.Token)})
+		}
+		writeJSON(w, tokens)
+		return
+	}
+	var body struct {
+		Label    string `json:"label"`
+		Campaign string `json:"campaign"`
+		expiryBody
+	}
+	if err := json.NewDecoder(io.LimitReader(r.Body, 4096)).Decode(&body); err != nil && err != io.EOF {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	change, ok := body.changeOrError(w)
+	if !ok {
+		return
+	}
+	var exp expiry
+	change(&exp)
+	token, err := correlations.create(strings.TrimSpace(body.Label), body.Campaign, exp)
+	if errors.Is(err, errCampaignNotFound) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	writeJSON(w, tokenWithURLs{token, payloadURLs(token.Token)})
+}
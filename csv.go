@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/csv"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// csvDefaultHeaders は export.csv に列として出すリクエストヘッダー。?headers=A,B で差し替えられる
+var csvDefaultHeaders = []string{"Referer", "X-Forwarded-For", "Content-Type"}
+
+// handleExportCSV は 1 エントリ 1 行の CSV を返す。HTTP 以外のエントリはメソッドやパスが空になる
+func handleExportCSV(w http.ResponseWriter, r *http.Request) {
+	logs, err := campaignLogs(r, maxLogs)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	headers := csvDefaultHeaders
+	if h := r.URL.Query().Get("headers"); h != "" {
+		headers = splitList(h)
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="ssrf_logs_`+time.Now().Format("20060102_150405")+`.csv"`)
+	w.Write([]byte("\xef\xbb\xbf")) // Excel で文字化けしないよう BOM を付ける
+
+	cw := csv.NewWriter(w)
+	cw.Write(append([]string{"id", "timestamp", "ip", "protocol", "method", "path", "host", "user_agent", "tags"}, headers...))
+	for _, entry := range logs {
+		row := []string{
+			strconv.FormatInt(entry.ID, 10), entry.Timestamp, entry.IP, entry.Protocol,
+			"", "", "", "", strings.Join(entry.Tags, " "),
+		}
+		req := entryRequest(entry)
+		if req != nil {
+			row[4], row[5], row[6], row[7] = req.Method, req.RequestURI, req.Host, req.UserAgent()
+		}
+		for _, name := range headers {
+			value := ""
+			if req != nil {
+				value = strings.Join(req.Header.Values(name), ", ")
+			}
+			row = append(row, value)
+		}
+		for i := range row {
+			row[i] = csvSafe(row[i])
+		}
+		cw.Write(row)
+	}
+	cw.Flush()
+}
+
+// csvSafe は表計算ソフトで数式として解釈される値の先頭に ' を付ける。
+// User-Agent などは攻撃者が自由に決められるため
+func csvSafe(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// deadLetterMax は保持する配送失敗の最大件数。超えたら古いものから捨てる
+const deadLetterMax = 500
+
+// deadLetter は再送しても届かなかった通知。管理画面から再送か破棄ができる
+type deadLetter struct {
+	ID       int
+	Notifier string
+	Entry    LogEntry
+	Error    string
+	FailedAt string
+	retry    func(LogEntry)
+}
+
+type deadLetterQueue struct {
+	mu      sync.Mutex
+	letters []deadLetter
+	nextID  int
+}
+
+var deadLetters = &deadLetterQueue{}
+
+// park は配送に失敗したエントリを残す。retry は管理画面から再送するときに呼ぶ
+func (q *deadLetterQueue) park(notifier string, entry LogEntry, err error, retry func(LogEntry)) {
+	fmt.Printf("%s Error: giving up on entry %d: %v\n", notifier, entry.ID, err)
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.nextID++
+	q.letters = append(q.letters, deadLetter{
+		ID:       q.nextID,
+		Notifier: notifier,
+		Entry:    entry,
+		Error:    err.Error(),
+		FailedAt: time.Now().Format("2006-01-02 15:04:05"),
+		retry:    retry,
+	})
+	if len(q.letters) > deadLetterMax {
+		q.letters = q.letters[len(q.letters)-deadLetterMax:]
+	}
+}
+
+func (q *deadLetterQueue) list() []deadLetter {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return append([]deadLetter(nil), q.letters...)
+}
+
+// take は id のものを取り除いて返す
+func (q *deadLetterQueue) take(id int) (deadLetter, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for i, l := range q.letters {
+		if l.ID == id {
+			q.letters = append(q.letters[:i:i], q.letters[i+1:]...)
+			return l, true
+		}
+	}
+	return deadLetter{}, false
+}
+
+// handleDeadLetter は POST /admin/deadletters/{id}/retry と /discard を受け付ける
+func handleDeadLetter(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+	action := r.PathValue("action")
+	if action != "retry" && action != "discard" {
+		http.NotFound(w, r)
+		return
+	}
+	letter, ok := deadLetters.take(id)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	if action == "retry" {
+		letter.retry(letter.Entry)
+	}
+	w.Write([]byte("ok"))
+}
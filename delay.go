@@ -0,0 +1,27 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxDelay は /delay/{seconds} で待てる上限
+const maxDelay = 10 * time.Minute
+
+// delayResponse は /delay/{seconds} を記録してから指定の秒数（小数可）待って応答する。
+// 被害側のタイムアウトを測ったり、時間差でブラインド SSRF を確かめたりする用
+func delayResponse(r *http.Request) *mockResponse {
+	rest, ok := strings.CutPrefix(r.URL.Path, "/delay/")
+	if !ok {
+		return nil
+	}
+	seconds, err := strconv.ParseFloat(rest, 64)
+	if err != nil || !(seconds >= 0 && seconds <= maxDelay.Seconds()) {
+		return &mockResponse{Status: http.StatusBadRequest, Body: fmt.Sprintf("seconds must be between 0 and %.0f", maxDelay.Seconds()), Tags: []string{"delay"}}
+	}
+	delay := time.Duration(seconds * float64(time.Second))
+	return &mockResponse{Status: http.StatusOK, Body: fmt.Sprintf("delayed %s", delay), Tags: []string{"delay"}, Delay: delay}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"strings"
+	"text/template"
+	"time"
+)
+
+const (
+	discordTitleLimit       = 256
+	discordDescriptionLimit = 4096
+	discordColor            = 0x1877f2
+)
+
+// newDiscordNotifier は Webhook に埋め込み（embed）付きのメッセージを送る。
+// 本文には生リクエストの先頭 lines 行をコードブロックで入れる。tmpl があれば埋め込みの代わりにその結果を content として送る
+func newDiscordNotifier(webhook string, lines int, tmpl *template.Template) *notifier {
+	return newNotifier("Discord", func(entry LogEntry) error {
+		if tmpl != nil {
+			content, err := renderNotify(tmpl, entry)
+			if err != nil {
+				return err
+			}
+			return postJSON(webhook, map[string]any{"content": content})
+		}
+		raw := strings.Split(strings.ReplaceAll(entry.RawRequest, "\r\n", "\n"), "\n")
+		if len(raw) > lines {
+			raw = append(raw[:lines], "...")
+		}
+		// コードブロックを閉じられないよう、中の ``` はゼロ幅スペースで崩す
+		code := strings.ReplaceAll(strings.Join(raw, "\n"), "```", "`\u200b``")
+		if limit := discordDescriptionLimit - len("```\n\n```"); len(code) > limit {
+			code = code[:limit]
+		}
+
+		fields := []map[string]any{
+			{"name": "Protocol", "value": entry.Protocol, "inline": true},
+			{"name": "IP", "value": entry.IP, "inline": true},
+		}
+		if len(entry.Tags) > 0 {
+			fields = append(fields, map[string]any{"name": "Tags", "value": strings.Join(entry.Tags, ", "), "inline": true})
+		}
+		title := entrySummary(entry)
+		if len(title) > discordTitleLimit {
+			title = title[:discordTitleLimit]
+		}
+		return postJSON(webhook, map[string]any{
+			"embeds": []map[string]any{{
+				"title":       title,
+				"url":         entryAdminURL(entry),
+				"description": "```\n" + code + "\n```",
+				"color":       discordColor,
+				"fields":      fields,
+				"timestamp":   time.Unix(0, entry.ID).UTC().Format(time.RFC3339),
+			}},
+		})
+	})
+}
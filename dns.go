@@ -0,0 +1,183 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+)
+
+const (
+	dnsTypeA    = 1
+	dnsTypeAAAA = 28
+	dnsClassIN  = 1
+
+	dnsRcodeNoError  = 0
+	dnsRcodeFormErr  = 1
+	dnsRcodeNXDomain = 3
+)
+
+var dnsTypeNames = map[uint16]string{
+	1:   "A",
+	2:   "NS",
+	5:   "CNAME",
+	6:   "SOA",
+	12:  "PTR",
+	15:  "MX",
+	16:  "TXT",
+	28:  "AAAA",
+	33:  "SRV",
+	255: "ANY",
+}
+
+type dnsQuestion struct {
+	Name  string
+	Type  uint16
+	Class uint16
+	end   int // 質問セクションの終端オフセット
+}
+
+func dnsTypeName(t uint16) string {
+	if name, ok := dnsTypeNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("TYPE%d", t)
+}
+
+// startDNSServer は serverDomain 配下の問い合わせに権威応答する UDP サーバーを起動する
+func startDNSServer(port, answerIP string) {
+	conn, err := net.ListenPacket("udp", ":"+port)
+	if err != nil {
+		fmt.Printf("DNS Error: %v\n", err)
+		return
+	}
+	defer conn.Close()
+
+	ip := net.ParseIP(answerIP)
+	buf := make([]byte, 512)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			fmt.Printf("DNS Error: %v\n", err)
+			continue
+		}
+		query := make([]byte, n)
+		copy(query, buf[:n])
+
+		clientIP, _, _ := net.SplitHostPort(addr.String())
+		resp := handleDNSQuery(query, clientIP, ip)
+		if resp != nil {
+			conn.WriteTo(resp, addr)
+		}
+	}
+}
+
+// handleDNSQuery は問い合わせを記録し、応答パケットを組み立てる
+func handleDNSQuery(query []byte, clientIP string, answerIP net.IP) []byte {
+	q, err := parseDNSQuestion(query)
+	if err != nil {
+		if len(query) < 12 {
+			return nil
+		}
+		addLog(newLogEntry("dns", clientIP, fmt.Sprintf("Malformed DNS query: %v\n\n%x", err, query), "FORMERR"))
+		return buildDNSResponse(query, 12, dnsRcodeFormErr, 0, nil)
+	}
+
+	rcode := dnsRcodeNoError
+	var answer []byte
+	var answerText string
+	name := strings.ToLower(strings.TrimSuffix(q.Name, "."))
+	domain := strings.ToLower(domainHost())
+	switch {
+	case name != domain && !strings.HasSuffix(name, "."+domain):
+		rcode = dnsRcodeNXDomain
+		answerText = "NXDOMAIN"
+	case q.Type == dnsTypeA && answerIP.To4() != nil:
+		answer = answerIP.To4()
+	case q.Type == dnsTypeAAAA && answerIP != nil && answerIP.To4() == nil:
+		answer = answerIP.To16()
+	}
+	if answer != nil {
+		answerText = fmt.Sprintf("%s\t60\tIN\t%s\t%s", q.Name, dnsTypeName(q.Type), answerIP)
+	} else if answerText == "" {
+		answerText = "NOERROR (no answer)"
+	}
+
+	rawRequest := fmt.Sprintf("QNAME: %s\nQTYPE: %s\nQCLASS: %d\nID: %d",
+		q.Name, dnsTypeName(q.Type), q.Class, binary.BigEndian.Uint16(query[0:2]))
+	addLog(newLogEntry("dns", clientIP, rawRequest, answerText))
+
+	return buildDNSResponse(query, q.end, rcode, q.Type, answer)
+}
+
+func parseDNSQuestion(msg []byte) (dnsQuestion, error) {
+	var q dnsQuestion
+	if len(msg) < 12 {
+		return q, errors.New("message too short")
+	}
+	if binary.BigEndian.Uint16(msg[4:6]) == 0 {
+		return q, errors.New("no question")
+	}
+
+	var labels []string
+	off := 12
+	for {
+		if off >= len(msg) {
+			return q, errors.New("truncated name")
+		}
+		l := int(msg[off])
+		off++
+		if l == 0 {
+			break
+		}
+		if l&0xC0 != 0 {
+			return q, errors.New("compressed name in question")
+		}
+		if off+l > len(msg) {
+			return q, errors.New("truncated label")
+		}
+		labels = append(labels, string(msg[off:off+l]))
+		off += l
+	}
+	if off+4 > len(msg) {
+		return q, errors.New("truncated question")
+	}
+
+	q.Name = strings.Join(labels, ".") + "."
+	q.Type = binary.BigEndian.Uint16(msg[off : off+2])
+	q.Class = binary.BigEndian.Uint16(msg[off+2 : off+4])
+	q.end = off + 4
+	return q, nil
+}
+
+// buildDNSResponse は質問セクションをそのまま返し、必要なら回答レコードを 1 件付ける
+func buildDNSResponse(query []byte, questionEnd, rcode int, qtype uint16, answer []byte) []byte {
+	resp := make([]byte, questionEnd, questionEnd+16+len(answer))
+	copy(resp, query[:questionEnd])
+
+	// QR=1, AA=1, Opcode と RD は問い合わせを引き継ぐ
+	resp[2] = 0x80 | (query[2] & 0x79) | 0x04
+	resp[3] = byte(rcode)
+	if questionEnd == 12 {
+		binary.BigEndian.PutUint16(resp[4:6], 0)
+	} else {
+		binary.BigEndian.PutUint16(resp[4:6], 1)
+	}
+	binary.BigEndian.PutUint16(resp[8:10], 0)
+	binary.BigEndian.PutUint16(resp[10:12], 0)
+
+	if answer == nil {
+		binary.BigEndian.PutUint16(resp[6:8], 0)
+		return resp
+	}
+	binary.BigEndian.PutUint16(resp[6:8], 1)
+	rr := make([]byte, 12)
+	binary.BigEndian.PutUint16(rr[0:2], 0xC00C) // 質問の名前へのポインタ
+	binary.BigEndian.PutUint16(rr[2:4], qtype)
+	binary.BigEndian.PutUint16(rr[4:6], dnsClassIN)
+	binary.BigEndian.PutUint32(rr[6:10], 60)
+	binary.BigEndian.PutUint16(rr[10:12], uint16(len(answer)))
+	resp = append(resp, rr...)
+	return append(resp, answer...)
+}
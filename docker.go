@@ -0,0 +1,46 @@
+package main
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+const dockerAPIVersion = "1.43"
+
+var dockerVersionPrefix = regexp.MustCompile(`^/v1\.\d+`)
+
+// emulateDockerAPI は Docker Engine API の主要なエンドポイントにそれらしい JSON を返す
+func emulateDockerAPI(r *http.Request) *mockResponse {
+	const tag = "docker-api"
+	path := dockerVersionPrefix.ReplaceAllString(r.URL.Path, "")
+
+	var res *mockResponse
+	switch strings.TrimSuffix(path, "/") {
+	case "/_ping":
+		res = textResponse(http.StatusOK, "OK", tag)
+	case "/version":
+		// -k8s も有効なら、Kubernetes のクライアントからのものはそちらに任せる
+		if k8sEnabled && k8sClient(r) {
+			return nil
+		}
+		res = jsonResponse(http.StatusOK, `{"Platform":{"Name":"Docker Engine - Community"},"Version":"24.0.7","ApiVersion":"1.43","MinAPIVersion":"1.12","GitCommit":"311b9ff","GoVersion":"go1.20.10","Os":"linux","Arch":"amd64","KernelVersion":"5.15.0-91-generic","BuildTime":"2023-10-26T09:08:02.000000000+00:00"}`, tag)
+	case "/info":
+		res = jsonResponse(http.StatusOK, `{"ID":"7TRN:IPZB:QYBB:VPBQ:UWYJ:KEKJ:4OCL:6NQY:2ZHK:SSQF:4NVI:EOPG","Containers":3,"ContainersRunning":2,"ContainersPaused":0,"ContainersStopped":1,"Images":7,"Driver":"overlay2","DockerRootDir":"/var/lib/docker","OperatingSystem":"Ubuntu 22.04.3 LTS","OSType":"linux","Architecture":"x86_64","NCPU":4,"MemTotal":16777216000,"Name":"build-runner-01","ServerVersion":"24.0.7"}`, tag)
+	case "/containers/json":
+		res = jsonResponse(http.StatusOK, `[{"Id":"8dfafdbc3a40b8f2c1e3d4a5b6c7d8e9f0a1b2c3d4e5f6a7b8c9d0e1f2a3b4c5","Names":["/app-web"],"Image":"registry.internal/app/web:2.14.1","ImageID":"sha256:2b8fd9751c4c0f5dd266fcae00707e67a2545ef34f9a29354585f93dac906749","Command":"/docker-entrypoint.sh","Created":1700000000,"Ports":[{"IP":"0.0.0.0","PrivatePort":8080,"PublicPort":80,"Type":"tcp"}],"Labels":{"com.docker.compose.project":"app"},"State":"running","Status":"Up 3 days"},{"Id":"4c01db0b339c5f8a1e2d3c4b5a69788796a5b4c3d2e1f0a9b8c7d6e5f4a3b2c1","Names":["/app-db"],"Image":"postgres:15","ImageID":"sha256:9f5d8e7c6b5a4938271605f4e3d2c1b0a9f8e7d6c5b4a39281706f5e4d3c2b1a","Command":"docker-entrypoint.sh postgres","Created":1700000000,"Ports":[{"PrivatePort":5432,"Type":"tcp"}],"Labels":{"com.docker.compose.project":"app"},"State":"running","Status":"Up 3 days"}]`, tag)
+	case "/images/json":
+		res = jsonResponse(http.StatusOK, `[{"Id":"sha256:2b8fd9751c4c0f5dd266fcae00707e67a2545ef34f9a29354585f93dac906749","ParentId":"","RepoTags":["registry.internal/app/web:2.14.1"],"RepoDigests":[],"Created":1699990000,"Size":182735616,"SharedSize":-1,"Labels":null,"Containers":1},{"Id":"sha256:9f5d8e7c6b5a4938271605f4e3d2c1b0a9f8e7d6c5b4a39281706f5e4d3c2b1a","ParentId":"","RepoTags":["postgres:15"],"RepoDigests":[],"Created":1699000000,"Size":412345678,"SharedSize":-1,"Labels":null,"Containers":1}]`, tag)
+	default:
+		if !strings.HasPrefix(path, "/containers/") && !strings.HasPrefix(path, "/images/") &&
+			!strings.HasPrefix(path, "/exec/") && path == r.URL.Path {
+			return nil
+		}
+		res = jsonResponse(http.StatusNotFound, `{"message":"page not found"}`, tag)
+	}
+	res.Header.Set("Api-Version", dockerAPIVersion)
+	res.Header.Set("Docker-Experimental", "false")
+	res.Header.Set("Ostype", "linux")
+	res.Header.Set("Server", "Docker/24.0.7 (linux)")
+	return res
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// tarpitInterval と tarpitDuration は -tarpit と -tarpit-duration。-rate-limit を超えたリクエストに
+// 429 をすぐ返す代わりに 1 バイトずつ送り、暴走したスキャナーの接続を長く引き止める
+var (
+	tarpitInterval time.Duration
+	tarpitDuration = time.Minute
+	// heldConns は -tarpit、/drip、/delay で今引き止めている接続の数
+	heldConns atomic.Int64
+)
+
+const (
+	// minDripInterval は /drip の interval と -tarpit の下限。細かすぎると tarpit にならない
+	minDripInterval = 10 * time.Millisecond
+	// maxHeldConns は同時に引き止める接続の上限。超えた分は -tarpit なら普通の 429、/drip と /delay なら 503 にする
+	maxHeldConns = 256
+)
+
+// acquireHold は接続を引き止める枠を 1 つ取る。上限に達していれば false。取れたら releaseHold で返す
+func acquireHold() bool {
+	if heldConns.Add(1) <= maxHeldConns {
+		return true
+	}
+	heldConns.Add(-1)
+	return false
+}
+
+func releaseHold() {
+	heldConns.Add(-1)
+}
+
+// busyResponse は引き止める枠が足りないときの 503
+func busyResponse(tags []string) *mockResponse {
+	return &mockResponse{Status: http.StatusServiceUnavailable, Header: http.Header{"Retry-After": {"1"}}, Body: "503 Service Unavailable\n", Tags: tags}
+}
+
+// dripResponse は /drip?interval=<ms>&duration=<seconds> を記録してから、ボディを interval（既定 1000 ms）ごとに
+// 1 バイトずつ duration（既定 60 秒）の間送る。被害側の読み込みのタイムアウトを見る用
+func dripResponse(r *http.Request) *mockResponse {
+	if r.URL.Path != "/drip" {
+		return nil
+	}
+	q := r.URL.Query()
+	interval, duration := time.Second, time.Minute
+	if s := q.Get("interval"); s != "" {
+		ms, err := strconv.Atoi(s)
+		if err != nil || time.Duration(ms)*time.Millisecond < minDripInterval {
+			return &mockResponse{Status: http.StatusBadRequest, Body: fmt.Sprintf("interval must be at least %d ms", minDripInterval.Milliseconds()), Tags: []string{"drip"}}
+		}
+		interval = time.Duration(ms) * time.Millisecond
+	}
+	if s := q.Get("duration"); s != "" {
+		seconds, err := strconv.ParseFloat(s, 64)
+		if err != nil || !(seconds > 0 && seconds <= maxDelay.Seconds()) {
+			return &mockResponse{Status: http.StatusBadRequest, Body: fmt.Sprintf("duration must be between 0 and %.0f", maxDelay.Seconds()), Tags: []string{"drip"}}
+		}
+		duration = time.Duration(seconds * float64(time.Second))
+	}
+	return &mockResponse{
+		Status:  http.StatusOK,
+		Body:    "Logged\n",
+		Tags:    []string{"drip"},
+		Drip:    interval,
+		DripFor: duration,
+	}
+}
+
+// drip はヘッダーをすぐ送り、ボディを繰り返しながら Drip ごとに 1 バイトずつ、DripFor が過ぎるか切断されるまで送る。
+// 送れたバイト列を返す
+func (m *mockResponse) drip(w http.ResponseWriter, r *http.Request) string {
+	for name, values := range m.Header {
+		w.Header()[name] = values
+	}
+	w.Header().Set("Content-Type", m.contentType())
+	w.WriteHeader(m.Status)
+	rc := http.NewResponseController(w)
+	rc.Flush()
+
+	body := m.Body
+	if body == "" {
+		body = "."
+	}
+	ticker := time.NewTicker(m.Drip)
+	defer ticker.Stop()
+	deadline := time.After(m.DripFor)
+	var sent strings.Builder
+	for i := 0; ; i++ {
+		select {
+		case <-ticker.C:
+			c := body[i%len(body)]
+			if _, err := w.Write([]byte{c}); err != nil {
+				return sent.String()
+			}
+			rc.Flush()
+			if sent.Len() < maxCaptureSize {
+				sent.WriteByte(c)
+			}
+		case <-deadline:
+			return sent.String()
+		case <-r.Context().Done():
+			return sent.String()
+		}
+	}
+}
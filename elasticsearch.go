@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// emulateElasticsearch はルートのバナーや _cluster/health など、Elasticsearch への典型的な探索に応答する
+func emulateElasticsearch(r *http.Request) *mockResponse {
+	const tag = "elasticsearch"
+	path := strings.TrimSuffix(r.URL.Path, "/")
+
+	var res *mockResponse
+	switch {
+	case path == "":
+		res = jsonResponse(http.StatusOK, `{
+  "name" : "es-node-01",
+  "cluster_name" : "prod-logs",
+  "cluster_uuid" : "Qx5lXv2tTnKpL0bM7yZ3aA",
+  "version" : {
+    "number" : "7.17.15",
+    "build_flavor" : "default",
+    "build_type" : "docker",
+    "build_hash" : "0b8ecfb4378335f4689c4223d1f1115f16bef3ba",
+    "build_date" : "2023-11-10T22:03:46.987399016Z",
+    "build_snapshot" : false,
+    "lucene_version" : "8.11.1",
+    "minimum_wire_compatibility_version" : "6.8.0",
+    "minimum_index_compatibility_version" : "6.0.0-beta1"
+  },
+  "tagline" : "You Know, for Search"
+}
+`, tag)
+	case path == "/_cluster/health":
+		res = jsonResponse(http.StatusOK, `{"cluster_name":"prod-logs","status":"green","timed_out":false,"number_of_nodes":3,"number_of_data_nodes":3,"active_primary_shards":24,"active_shards":48,"relocating_shards":0,"initializing_shards":0,"unassigned_shards":0,"delayed_unassigned_shards":0,"number_of_pending_tasks":0,"number_of_in_flight_fetch":0,"task_max_waiting_in_queue_millis":0,"active_shards_percent_as_number":100.0}`, tag)
+	case path == "/_cat/indices":
+		res = textResponse(http.StatusOK, strings.Join([]string{
+			"green open app-logs-2024.01    kT3cM1fZQ6mJ0m0s8wD8xA 1 1 1843201 0   1.2gb 614.3mb",
+			"green open users               Hq9bW2rXR7uV4dZ0k1yLmQ 1 1   48210 0  38.4mb  19.2mb",
+			"green open payments            Zp2nC8vTSmGy5eJ3r6wKxB 1 1  120934 0 104.7mb  52.3mb",
+			"",
+		}, "\n"), tag)
+	case path == "/_cat/health":
+		res = textResponse(http.StatusOK, "1700000000 00:00:00 prod-logs green 3 3 48 24 0 0 0 0 - 100.0%\n", tag)
+	case path == "/_nodes" || strings.HasPrefix(path, "/_nodes/"):
+		res = jsonResponse(http.StatusOK, `{"_nodes":{"total":3,"successful":3,"failed":0},"cluster_name":"prod-logs","nodes":{"x1Yb2cZ3Q4uV5wX6yZ7a8B":{"name":"es-node-01","transport_address":"10.0.3.11:9300","host":"10.0.3.11","ip":"10.0.3.11","version":"7.17.15","roles":["data","ingest","master"]}}}`, tag)
+	case path == "/_search" || strings.HasSuffix(path, "/_search"):
+		res = jsonResponse(http.StatusOK, `{"took":3,"timed_out":false,"_shards":{"total":1,"successful":1,"skipped":0,"failed":0},"hits":{"total":{"value":0,"relation":"eq"},"max_score":null,"hits":[]}}`, tag)
+	case strings.HasPrefix(path, "/_"):
+		res = jsonResponse(http.StatusBadRequest, fmt.Sprintf(`{"error":"no handler found for uri [%s] and method [%s]"}`, r.URL.Path, r.Method), tag)
+	default:
+		return nil
+	}
+	res.Header.Set("X-Elastic-Product", "Elasticsearch")
+	return res
+}
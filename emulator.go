@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// mockResponse はキャッチオールが返す応答。記録用の生レスポンスもここから組み立てる
+type mockResponse struct {
+	Status int
+	Header http.Header
+	Body   string
+	Tags   []string
+	Delay  time.Duration // 記録してから応答するまで待つ時間
+
+	// Drip が 0 でなければボディを Drip ごとに 1 バイトずつ、DripFor の間送り続ける
+	Drip, DripFor time.Duration
+}
+
+// emulator はリクエストが自分の担当なら応答を返し、そうでなければ nil を返す
+type emulator func(r *http.Request) *mockResponse
+
+// emulators は起動フラグに応じて登録され、handleAll で先頭から順に試される
+var emulators []emulator
+
+// builtinResponders は常に有効な /s/、/status/ などの応答。emulators の後に試される
+var builtinResponders = []emulator{shortLinkResponse, statusResponse, redirectResponse, chainResponse, delayResponse, dripResponse}
+
+func matchEmulators(r *http.Request) *mockResponse {
+	for _, emulate := range emulators {
+		if res := emulate(r); res != nil {
+			return res
+		}
+	}
+	for _, respond := range builtinResponders {
+		if res := respond(r); res != nil {
+			return res
+		}
+	}
+	return nil
+}
+
+func (m *mockResponse) contentType() string {
+	if ct := m.Header.Get("Content-Type"); ct != "" {
+		return ct
+	}
+	return "text/plain; charset=utf-8"
+}
+
+// raw は記録用に HTTP レスポンスをテキストで再構成する
+func (m *mockResponse) raw(proto string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %d %s\n", proto, m.Status, http.StatusText(m.Status))
+	fmt.Fprintf(&b, "Date: %s\n", time.Now().UTC().Format(http.TimeFormat))
+	fmt.Fprintf(&b, "Content-Type: %s\n", m.contentType())
+
+	names := make([]string, 0, len(m.Header))
+	for name := range m.Header {
+		if name != "Content-Type" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, v := range m.Header[name] {
+			fmt.Fprintf(&b, "%s: %s\n", name, v)
+		}
+	}
+	if m.Drip > 0 {
+		// drip は長さを決めずに少しずつ送るので、ボディは送り終えてから実際に送った分を足す
+		b.WriteString("Transfer-Encoding: chunked\n\n")
+		return b.String()
+	}
+	fmt.Fprintf(&b, "Content-Length: %d\n\n%s", len(m.Body), m.Body)
+	return b.String()
+}
+
+func (m *mockResponse) write(w http.ResponseWriter) {
+	for name, values := range m.Header {
+		w.Header()[name] = values
+	}
+	w.Header().Set("Content-Type", m.contentType())
+	w.WriteHeader(m.Status)
+	w.Write([]byte(m.Body))
+}
+
+// jsonResponse は JSON ボディの mockResponse を作る
+func jsonResponse(status int, body string, tags ...string) *mockResponse {
+	return &mockResponse{
+		Status: status,
+		Header: http.Header{"Content-Type": {"application/json"}},
+		Body:   body,
+		Tags:   tags,
+	}
+}
+
+// textResponse はテキストボディの mockResponse を作る
+func textResponse(status int, body string, tags ...string) *mockResponse {
+	return &mockResponse{Status: status, Header: http.Header{}, Body: body, Tags: tags}
+}
+
+// xmlResponse は XML ボディの mockResponse を作る
+func xmlResponse(status int, body string, tags ...string) *mockResponse {
+	return &mockResponse{
+		Status: status,
+		Header: http.Header{"Content-Type": {"application/xml; charset=utf-8"}},
+		Body:   body,
+		Tags:   tags,
+	}
+}
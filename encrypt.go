@@ -0,0 +1,129 @@
+package main
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+)
+
+// encryptedPrefix は暗号化済みのフィールドに付ける目印。付いていない値は平文のまま扱う
+const encryptedPrefix = "enc:v1:"
+
+var errEncryptKey = errors.New("-encrypt-key must be 32 random bytes in hex or base64 (e.g. openssl rand -hex 32)")
+
+// entryCipher は生リクエスト・生レスポンスを AES-256-GCM で暗号化する。鍵は -encrypt-key の 32 バイト。
+// 一覧や絞り込みに使う Host・パス・トークン・メモ・クライアント証明書などのフィールドは平文のまま残す
+type entryCipher struct {
+	aead cipher.AEAD
+}
+
+// newEntryCipher は hex か base64 で書いた 32 バイトの鍵を受け付ける。
+// 推測できるパスフレーズをそのまま鍵にしないよう、長さの違うものは断る
+func newEntryCipher(encoded string) (*entryCipher, error) {
+	encoded = strings.TrimSpace(encoded)
+	key, err := hex.DecodeString(encoded)
+	if err != nil {
+		key, err = base64.StdEncoding.DecodeString(encoded)
+	}
+	if err != nil || len(key) != 32 {
+		return nil, errEncryptKey
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &entryCipher{aead: aead}, nil
+}
+
+func (c *entryCipher) encrypt(s string) string {
+	if s == "" {
+		return s
+	}
+	nonce := make([]byte, c.aead.NonceSize())
+	rand.Read(nonce)
+	return encryptedPrefix + base64.StdEncoding.EncodeToString(c.aead.Seal(nonce, nonce, []byte(s), nil))
+}
+
+func (c *entryCipher) decrypt(s string) (string, error) {
+	data, ok := strings.CutPrefix(s, encryptedPrefix)
+	if !ok {
+		return s, nil
+	}
+	raw, err := base64.StdEncoding.DecodeString(data)
+	if err != nil || len(raw) < c.aead.NonceSize() {
+		return s, errors.New("broken ciphertext")
+	}
+	n := c.aead.NonceSize()
+	plain, err := c.aead.Open(nil, raw[:n], raw[n:], nil)
+	if err != nil {
+		return s, errors.New("cannot decrypt entry (wrong -encrypt-key?)")
+	}
+	return string(plain), nil
+}
+
+func (c *entryCipher) seal(entry LogEntry) LogEntry {
+	entry.RawRequest = c.encrypt(entry.RawRequest)
+	entry.RawResponse = c.encrypt(entry.RawResponse)
+	return entry
+}
+
+// open は復号できなかったフィールドを暗号文のまま残す
+func (c *entryCipher) open(entry LogEntry) LogEntry {
+	entry.RawRequest, _ = c.decrypt(entry.RawRequest)
+	entry.RawResponse, _ = c.decrypt(entry.RawResponse)
+	return entry
+}
+
+// encryptedStore は書き込み時に暗号化し、読み出し時に復号する Storage のラッパー
+type encryptedStore struct {
+	Storage
+	cipher *entryCipher
+}
+
+func (s encryptedStore) Append(entry LogEntry) error {
+	return s.Storage.Append(s.cipher.seal(entry))
+}
+
+func (s encryptedStore) Update(entry LogEntry) error {
+	return s.Storage.Update(s.cipher.seal(entry))
+}
+
+func (s encryptedStore) List(limit int) ([]LogEntry, error) {
+	return s.openAll(s.Storage.List(limit))
+}
+
+func (s encryptedStore) Page(offset, limit int) ([]LogEntry, error) {
+	return s.openAll(s.Storage.Page(offset, limit))
+}
+
+func (s encryptedStore) Since(id int64, limit int) ([]LogEntry, error) {
+	return s.openAll(s.Storage.Since(id, limit))
+}
+
+func (s encryptedStore) Each(fn func(LogEntry) bool) error {
+	return s.Storage.Each(func(entry LogEntry) bool { return fn(s.cipher.open(entry)) })
+}
+
+func (s encryptedStore) PageState(f stateFilter, offset, limit int) ([]LogEntry, error) {
+	return s.openAll(s.Storage.PageState(f, offset, limit))
+}
+
+func (s encryptedStore) openAll(logs []LogEntry, err error) ([]LogEntry, error) {
+	for i := range logs {
+		logs[i] = s.cipher.open(logs[i])
+	}
+	return logs, err
+}
+
+func (s encryptedStore) Get(id int64) (LogEntry, bool, error) {
+	entry, ok, err := s.Storage.Get(id)
+	return s.cipher.open(entry), ok, err
+}
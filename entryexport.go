@@ -0,0 +1,177 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// curlSkipHeaders は curl が自分で付けるため、コマンドに含めないヘッダー
+var curlSkipHeaders = map[string]bool{"Content-Length": true, "Connection": true, "Accept-Encoding": true}
+
+// handleEntryExport は /api/logs/{id}/export?format=txt|http|curl で 1 件分をファイルとして返す。
+// http は VS Code REST Client の .http、curl はそのまま実行できるシェルスクリプト（target は handleEntryCurl と同じ）
+func handleEntryExport(w http.ResponseWriter, r *http.Request) {
+	entry, ok := entryFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	name := fmt.Sprintf("%s_%s", domainHost(), entry.FilenameTS)
+	var body string
+	switch format {
+	case "", "txt":
+		body = fmt.Sprintf("=== REQUEST ===\n%s\n\n=== RESPONSE ===\n%s", entry.RawRequest, entry.RawResponse)
+		name += ".txt"
+	case "http", "curl":
+		req := entryRequest(entry)
+		if req == nil {
+			http.Error(w, "not an HTTP entry", http.StatusUnprocessableEntity)
+			return
+		}
+		if format == "http" {
+			body, name = restClientFile(entry, req), name+".http"
+			break
+		}
+		line, err := curlCommand(req, harURL(entry, req), r.URL.Query().Get("target"), r.URL.Query().Get("keep_host") == "1")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		body = fmt.Sprintf("#!/bin/sh\n# %s from %s at %s\n%s", entry.Protocol, entry.IP, entry.Timestamp, line)
+		name += ".sh"
+	default:
+		http.Error(w, "format must be txt, http or curl", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+name+`"`)
+	io.WriteString(w, body)
+}
+
+// restClientFile は VS Code REST Client 形式のリクエストを組み立てる
+func restClientFile(entry LogEntry, req *http.Request) string {
+	body, _ := io.ReadAll(req.Body)
+	var b strings.Builder
+	fmt.Fprintf(&b, "### %s from %s at %s\n", entry.Protocol, entry.IP, entry.Timestamp)
+	fmt.Fprintf(&b, "%s %s HTTP/1.1\n", req.Method, harURL(entry, req))
+	for _, name := range sortedHeaderNames(req.Header) {
+		for _, v := range req.Header[name] {
+			fmt.Fprintf(&b, "%s: %s\n", name, v)
+		}
+	}
+	if len(body) > 0 {
+		b.WriteString("\n")
+		b.Write(body)
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// handleEntryCurl は GET /api/logs/{id}/curl で同じリクエストを再送する curl コマンドを 1 つ返す（クリップボード用）。
+// target=http://localhost:8080 を付けるとスキームとホストを差し替え、keep_host=1 なら元の Host ヘッダーを付ける
+func handleEntryCurl(w http.ResponseWriter, r *http.Request) {
+	entry, ok := entryFromPath(w, r)
+	if !ok {
+		return
+	}
+	req := entryRequest(entry)
+	if req == nil {
+		http.Error(w, "not an HTTP entry", http.StatusUnprocessableEntity)
+		return
+	}
+	line, err := curlCommand(req, harURL(entry, req), r.URL.Query().Get("target"), r.URL.Query().Get("keep_host") == "1")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	io.WriteString(w, line)
+}
+
+// entryFromPath はパスの {id} のエントリを読む。見つからなければエラーを返して false
+func entryFromPath(w http.ResponseWriter, r *http.Request) (LogEntry, bool) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return LogEntry{}, false
+	}
+	entry, ok, err := store.Get(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return LogEntry{}, false
+	}
+	if !ok {
+		http.NotFound(w, r)
+	}
+	return entry, ok
+}
+
+// retarget は rawURL のスキームとホストを target のものに差し替え、target にパスがあれば先頭に付ける
+func retarget(rawURL, target string) (string, error) {
+	t, err := url.Parse(target)
+	if err != nil || (t.Scheme != "http" && t.Scheme != "https") || t.Host == "" {
+		return "", fmt.Errorf("target must be an http(s) URL such as http://localhost:8080")
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	u.Scheme, u.Host = t.Scheme, t.Host
+	u.Path = strings.TrimSuffix(t.Path, "/") + u.Path
+	u.RawPath = ""
+	return u.String(), nil
+}
+
+// curlCommand は同じリクエストを再送する curl コマンドを組み立てる。target が空でなければ送り先を差し替える
+func curlCommand(req *http.Request, rawURL, target string, keepHost bool) (string, error) {
+	if target != "" {
+		var err error
+		if rawURL, err = retarget(rawURL, target); err != nil {
+			return "", err
+		}
+	}
+	body, _ := io.ReadAll(req.Body)
+	var b strings.Builder
+	b.WriteString("curl -sS -i")
+	if req.Method != http.MethodGet || len(body) > 0 {
+		fmt.Fprintf(&b, " -X %s", shellQuote(req.Method))
+	}
+	fmt.Fprintf(&b, " %s", shellQuote(rawURL))
+	if target != "" && keepHost && req.Host != "" {
+		fmt.Fprintf(&b, " \\\n  -H %s", shellQuote("Host: "+req.Host))
+	}
+	for _, name := range sortedHeaderNames(req.Header) {
+		if curlSkipHeaders[name] {
+			continue
+		}
+		for _, v := range req.Header[name] {
+			fmt.Fprintf(&b, " \\\n  -H %s", shellQuote(name+": "+v))
+		}
+	}
+	if len(body) > 0 {
+		fmt.Fprintf(&b, " \\\n  --data-binary %s", shellQuote(string(body)))
+	}
+	b.WriteString("\n")
+	return b.String(), nil
+}
+
+// shellQuote は値をシングルクォートで囲む。中のシングルクォートは '\” に置き換える
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func sortedHeaderNames(header http.Header) []string {
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
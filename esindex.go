@@ -0,0 +1,206 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// esIndexMapping は Kibana で集計しやすいように、IP やプロトコルは keyword、ヘッダーは nested にする
+const esIndexMapping = `{
+  "mappings": {
+    "properties": {
+      "@timestamp":    {"type": "date"},
+      "entry_id":      {"type": "keyword"},
+      "parent_id":     {"type": "keyword"},
+      "protocol":      {"type": "keyword"},
+      "proto_version": {"type": "keyword"},
+      "ip":            {"type": "keyword"},
+      "tags":          {"type": "keyword"},
+      "raw_request":   {"type": "text"},
+      "raw_response":  {"type": "text"},
+      "tls": {
+        "properties": {
+          "sni":       {"type": "keyword"},
+          "version":   {"type": "keyword"},
+          "ja3_hash":  {"type": "keyword"},
+          "ja3s_hash": {"type": "keyword"}
+        }
+      },
+      "http": {
+        "properties": {
+          "method":     {"type": "keyword"},
+          "url":        {"type": "keyword", "fields": {"text": {"type": "text"}}},
+          "host":       {"type": "keyword"},
+          "path":       {"type": "keyword"},
+          "user_agent": {"type": "keyword", "fields": {"text": {"type": "text"}}},
+          "headers": {
+            "type": "nested",
+            "properties": {
+              "name":  {"type": "keyword"},
+              "value": {"type": "keyword", "ignore_above": 1024, "fields": {"text": {"type": "text"}}}
+            }
+          }
+        }
+      }
+    }
+  }
+}`
+
+// esIndexer は新しいエントリを _bulk API で Elasticsearch / OpenSearch のインデックスに入れる
+type esIndexer struct {
+	*batchSink
+	url      string
+	index    string
+	user     string
+	password string
+	client   *http.Client
+}
+
+type esDocument struct {
+	Timestamp    time.Time `json:"@timestamp"`
+	EntryID      string    `json:"entry_id"`
+	ParentID     string    `json:"parent_id,omitempty"`
+	Protocol     string    `json:"protocol"`
+	ProtoVersion string    `json:"proto_version,omitempty"`
+	IP           string    `json:"ip"`
+	Tags         []string  `json:"tags,omitempty"`
+	RawRequest   string    `json:"raw_request"`
+	RawResponse  string    `json:"raw_response"`
+	TLS          *TLSInfo  `json:"tls,omitempty"`
+	HTTP         *esHTTP   `json:"http,omitempty"`
+}
+
+type esHTTP struct {
+	Method    string     `json:"method"`
+	URL       string     `json:"url"`
+	Host      string     `json:"host"`
+	Path      string     `json:"path"`
+	UserAgent string     `json:"user_agent,omitempty"`
+	Headers   []esHeader `json:"headers"`
+}
+
+type esHeader struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// newESIndexer はインデックスがなければマッピング付きで作る
+func newESIndexer(target, index, user, password string) (*esIndexer, error) {
+	e := &esIndexer{
+		url:      strings.TrimSuffix(target, "/"),
+		index:    index,
+		user:     user,
+		password: password,
+		client:   &http.Client{Timeout: 30 * time.Second},
+	}
+	e.batchSink = newBatchSink("Elasticsearch", e.send)
+
+	status, body, err := e.do(http.MethodPut, "/"+index, "application/json", []byte(esIndexMapping))
+	if err != nil {
+		return nil, err
+	}
+	if status/100 != 2 && !bytes.Contains(body, []byte("resource_already_exists_exception")) {
+		return nil, fmt.Errorf("create index %s: %d: %s", index, status, body)
+	}
+	return e, nil
+}
+
+func (e *esIndexer) do(method, path, contentType string, body []byte) (int, []byte, error) {
+	req, err := http.NewRequest(method, e.url+path, bytes.NewReader(body))
+	if err != nil {
+		return 0, nil, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	if e.user != "" {
+		req.SetBasicAuth(e.user, e.password)
+	}
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	return resp.StatusCode, data, err
+}
+
+// send はエントリ ID をドキュメント ID にするので、再送しても重複しない
+func (e *esIndexer) send(batch []LogEntry) error {
+	var body bytes.Buffer
+	enc := json.NewEncoder(&body)
+	for _, entry := range batch {
+		enc.Encode(map[string]any{"index": map[string]string{"_index": e.index, "_id": fmt.Sprint(entry.ID)}})
+		enc.Encode(esDocumentFromEntry(entry))
+	}
+
+	var result struct {
+		Errors bool `json:"errors"`
+		Items  []map[string]struct {
+			Status int             `json:"status"`
+			Error  json.RawMessage `json:"error"`
+		} `json:"items"`
+	}
+	err := withRetry(func() (bool, error) {
+		status, data, err := e.do(http.MethodPost, "/_bulk", "application/x-ndjson", body.Bytes())
+		if err != nil {
+			return true, err
+		}
+		if status/100 != 2 {
+			return retryableStatus(status), fmt.Errorf("POST /_bulk: %d: %s", status, bytes.TrimSpace(data))
+		}
+		return false, json.Unmarshal(data, &result)
+	})
+	if err != nil {
+		return err
+	}
+
+	// マッピングに合わないなど個別に失敗したドキュメントは送り直しても通らないので、件数だけ出して捨てる
+	if result.Errors {
+		failed := 0
+		var first json.RawMessage
+		for _, item := range result.Items {
+			for _, r := range item {
+				if r.Status/100 != 2 {
+					failed++
+					if first == nil {
+						first = r.Error
+					}
+				}
+			}
+		}
+		fmt.Printf("Elasticsearch Error: %d of %d documents failed: %s\n", failed, len(batch), first)
+	}
+	return nil
+}
+
+func esDocumentFromEntry(entry LogEntry) esDocument {
+	doc := esDocument{
+		Timestamp:    time.Unix(0, entry.ID).UTC(),
+		EntryID:      fmt.Sprint(entry.ID),
+		Protocol:     entry.Protocol,
+		ProtoVersion: entry.ProtoVersion,
+		IP:           entry.IP,
+		Tags:         entry.Tags,
+		RawRequest:   entry.RawRequest,
+		RawResponse:  entry.RawResponse,
+		TLS:          entry.TLS,
+	}
+	if entry.ParentID != 0 {
+		doc.ParentID = fmt.Sprint(entry.ParentID)
+	}
+	if req := entryRequest(entry); req != nil {
+		h := &esHTTP{Method: req.Method, URL: harURL(entry, req), Host: req.Host, Path: req.URL.Path, UserAgent: req.UserAgent()}
+		h.Headers = append(h.Headers, esHeader{"Host", req.Host})
+		for _, name := range sortedHeaderNames(req.Header) {
+			for _, v := range req.Header[name] {
+				h.Headers = append(h.Headers, esHeader{name, v})
+			}
+		}
+		doc.HTTP = h
+	}
+	return doc
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+)
+
+// expiry は相関トークンとキャンペーンの有効期限。期限を過ぎたペイロード URL は 410 を返し、届いても通知しない
+type expiry struct {
+	Expires time.Time `json:"expires,omitzero"`
+	Purge   bool      `json:"purge,omitempty"` // 期限を過ぎたら紐づくエントリを削除する
+}
+
+func (e expiry) expired(now time.Time) bool {
+	return !e.Expires.IsZero() && now.After(e.Expires)
+}
+
+// parseExpiry は RFC3339 の時刻か、今からの期間（72h など）を読む。空なら期限なし
+func parseExpiry(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return time.Now().Add(d), nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, errors.New(`expires must be RFC3339 or a duration like "72h"`)
+	}
+	return t, nil
+}
+
+// expiryBody は作成・変更のリクエストで受け取る期限。nil の項目は変えない
+type expiryBody struct {
+	Expires *string `json:"expires"`
+	Purge   *bool   `json:"purge"`
+}
+
+// errReadOnlyExpiry は -readonly で期限や削除の設定を変えようとしたとき。期限後の削除でログが消えるので受け付けない
+var errReadOnlyExpiry = errors.New("expires and purge cannot be changed in read-only mode")
+
+// change は指定された項目だけを書き換える関数を返す
+func (b expiryBody) change() (func(*expiry), error) {
+	if readOnly && (b.Expires != nil || b.Purge != nil) {
+		return nil, errReadOnlyExpiry
+	}
+	var expires time.Time
+	if b.Expires != nil {
+		var err error
+		if expires, err = parseExpiry(*b.Expires); err != nil {
+			return nil, err
+		}
+	}
+	return func(e *expiry) {
+		if b.Expires != nil {
+			e.Expires = expires
+		}
+		if b.Purge != nil {
+			e.Purge = *b.Purge
+		}
+	}, nil
+}
+
+// changeOrError は change の結果を返し、エラーなら応答を書いて false を返す
+func (b expiryBody) changeOrError(w http.ResponseWriter) (func(*expiry), bool) {
+	change, err := b.change()
+	if errors.Is(err, errReadOnlyExpiry) {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return nil, false
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+	return change, true
+}
+
+// tokenExpiry は相関トークン自身かキャンペーンの期限が切れていれば、どちらかの期限を返す。呼び出し側でロックを取っておく
+func (s *correlationStore) tokenExpiry(t correlationToken, now time.Time) (expiry, bool) {
+	if t.expired(now) {
+		return t.expiry, true
+	}
+	if i := s.campaignIndex(t.Campaign); t.Campaign != "" && i >= 0 && s.campaigns[i].expired(now) {
+		return s.campaigns[i].expiry, true
+	}
+	return expiry{}, false
+}
+
+// expired は発行した相関トークンの期限（キャンペーンの期限を含む）が切れているかを返す
+func (s *correlationStore) expired(token string) bool {
+	if token == "" {
+		return false
+	}
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, t := range s.tokens {
+		if t.Token == token {
+			_, ok := s.tokenExpiry(t, now)
+			return ok
+		}
+	}
+	return false
+}
+
+// purgeTargets は期限が切れて削除を指定された相関トークン
+func (s *correlationStore) purgeTargets() []string {
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var tokens []string
+	for _, t := range s.tokens {
+		if e, ok := s.tokenExpiry(t, now); ok && e.Purge {
+			tokens = append(tokens, t.Token)
+		}
+	}
+	return tokens
+}
+
+// requestExpired は期限切れの相関トークン宛てのリクエストかを返す。ペイロード URL を 410 にするのに使う
+func requestExpired(r *http.Request) bool {
+	return correlations.expired(withCorrelation(LogEntry{Host: r.Host, Path: r.URL.Path}).Token)
+}
+
+// startCorrelationPurge は期限切れで削除を指定された相関トークンのエントリを定期的に削除する。期限後に届いたものも次の周期で消える
+func startCorrelationPurge(s Storage) {
+	for {
+		time.Sleep(time.Minute)
+		targets := correlations.purgeTargets()
+		if len(targets) == 0 {
+			continue
+		}
+		// 読みながら消すと位置がずれるので、先に ID を集めてから消す
+		var ids []int64
+		err := s.Each(func(entry LogEntry) bool {
+			if entry.Token != "" && slices.Contains(targets, entry.Token) {
+				ids = append(ids, entry.ID)
+			}
+			return true
+		})
+		if err != nil {
+			fmt.Printf("Purge Error: %v\n", err)
+			continue
+		}
+		n := 0
+		for _, id := range ids {
+			if err := s.Delete(id); err != nil {
+				fmt.Printf("Purge Error: %v\n", err)
+				continue
+			}
+			n++
+		}
+		if n > 0 {
+			fmt.Printf("Purge: deleted %d entries of expired tokens\n", n)
+		}
+	}
+}
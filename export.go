@@ -0,0 +1,214 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// exportMaxPending はアップロードに失敗し続けたときに溜めておく最大件数
+const exportMaxPending = 100000
+
+// bucketExporter は新しいエントリを溜めておき、一定間隔で gzip した JSONL として
+// S3 または GCS（HMAC キーによる S3 互換 API）にアップロードする
+type bucketExporter struct {
+	mu      sync.Mutex
+	pending []LogEntry
+
+	endpoint  string // https://s3.<region>.amazonaws.com など
+	bucket    string
+	prefix    string
+	region    string
+	accessKey string
+	secretKey string
+	token     string
+	client    *http.Client
+}
+
+var exporter *bucketExporter
+
+// newBucketExporter は s3://bucket/prefix か gs://bucket/prefix を受け取る。
+// 認証情報が空なら AWS_ACCESS_KEY_ID などの環境変数を使う
+func newBucketExporter(target, region, endpoint, accessKey, secretKey string) (*bucketExporter, error) {
+	u, err := url.Parse(target)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid export target %q", target)
+	}
+	e := &bucketExporter{
+		endpoint:  strings.TrimSuffix(endpoint, "/"),
+		bucket:    u.Host,
+		prefix:    strings.Trim(u.Path, "/"),
+		region:    region,
+		accessKey: accessKey,
+		secretKey: secretKey,
+		token:     os.Getenv("AWS_SESSION_TOKEN"),
+		client:    &http.Client{Timeout: time.Minute},
+	}
+	if e.accessKey == "" {
+		e.accessKey = os.Getenv("AWS_ACCESS_KEY_ID")
+	}
+	if e.secretKey == "" {
+		e.secretKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+	}
+	switch u.Scheme {
+	case "s3":
+		if e.endpoint == "" {
+			e.endpoint = "https://s3." + e.region + ".amazonaws.com"
+		}
+	case "gs":
+		if e.endpoint == "" {
+			e.endpoint = "https://storage.googleapis.com"
+		}
+		e.region = "auto"
+	default:
+		return nil, fmt.Errorf("unsupported export scheme %q (s3 or gs)", u.Scheme)
+	}
+	if e.accessKey == "" || e.secretKey == "" {
+		return nil, fmt.Errorf("export credentials are not set")
+	}
+	return e, nil
+}
+
+func (e *bucketExporter) add(entry LogEntry) {
+	e.mu.Lock()
+	if len(e.pending) < exportMaxPending {
+		e.pending = append(e.pending, entry)
+	}
+	e.mu.Unlock()
+}
+
+// run は interval ごとに溜まった分をアップロードする。失敗したら次回にまとめて再送する
+func (e *bucketExporter) run(interval time.Duration) {
+	for range time.Tick(interval) {
+		e.mu.Lock()
+		batch := e.pending
+		e.pending = nil
+		e.mu.Unlock()
+		if len(batch) == 0 {
+			continue
+		}
+
+		key, err := e.upload(batch)
+		if err != nil {
+			fmt.Printf("Export Error: %v\n", err)
+			e.mu.Lock()
+			e.pending = append(batch, e.pending...)
+			if len(e.pending) > exportMaxPending {
+				e.pending = e.pending[len(e.pending)-exportMaxPending:]
+			}
+			e.mu.Unlock()
+			continue
+		}
+		fmt.Printf("Export: uploaded %d entries to %s/%s\n", len(batch), e.bucket, key)
+	}
+}
+
+func (e *bucketExporter) upload(batch []LogEntry) (string, error) {
+	var body bytes.Buffer
+	gz := gzip.NewWriter(&body)
+	enc := json.NewEncoder(gz)
+	for _, entry := range batch {
+		if err := enc.Encode(entry); err != nil {
+			return "", err
+		}
+	}
+	if err := gz.Close(); err != nil {
+		return "", err
+	}
+
+	now := time.Now().UTC()
+	key := fmt.Sprintf("%s/%d-%d.jsonl.gz", now.Format("2006/01/02"), batch[0].ID, batch[len(batch)-1].ID)
+	if e.prefix != "" {
+		key = e.prefix + "/" + key
+	}
+
+	req, err := http.NewRequest(http.MethodPut, e.endpoint+"/"+e.bucket+"/"+key, bytes.NewReader(body.Bytes()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	req.Header.Set("Content-Encoding", "gzip")
+	signV4(req, body.Bytes(), e.accessKey, e.secretKey, e.token, e.region, now)
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return "", fmt.Errorf("PUT %s: %s: %s", key, resp.Status, msg)
+	}
+	return key, nil
+}
+
+// signV4 は AWS Signature Version 4 で req に署名する（サービスは s3、クエリ文字列なし）
+func signV4(req *http.Request, payload []byte, accessKey, secretKey, token, region string, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	payloadHash := sha256Hex(payload)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if token != "" {
+		req.Header.Set("X-Amz-Security-Token", token)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	scope := date + "/" + region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	key := hmacSHA256([]byte("AWS4"+secretKey), date)
+	for _, part := range []string{region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		accessKey, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
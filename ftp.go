@@ -0,0 +1,61 @@
+package main
+
+import (
+	"net"
+	"strings"
+)
+
+// handleFTP は制御チャネルのみを持つ FTP サーバーとして振る舞う（データ転送は行わない）
+func handleFTP(conn net.Conn) {
+	s := newSession(conn)
+	defer s.save("ftp")
+
+	s.reply("220 %s FTP server ready", domainHost())
+	for {
+		line, err := s.readLine()
+		if err != nil {
+			return
+		}
+		cmd, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(cmd) {
+		case "USER":
+			s.reply("331 Password required for %s", arg)
+		case "PASS":
+			s.reply("230 User logged in")
+		case "SYST":
+			s.reply("215 UNIX Type: L8")
+		case "FEAT":
+			s.reply("211-Features:")
+			s.reply(" PASV")
+			s.reply(" EPSV")
+			s.reply("211 End")
+		case "PWD", "XPWD":
+			s.reply("257 \"/\" is the current directory")
+		case "CWD", "CDUP":
+			s.reply("250 Directory successfully changed")
+		case "TYPE", "MODE", "STRU", "PORT", "EPRT", "NOOP":
+			s.reply("200 Command okay")
+		case "PASV":
+			// 実際にはデータ接続を待ち受けないので、クライアントは後続の LIST/RETR で失敗する
+			host, _, _ := net.SplitHostPort(conn.LocalAddr().String())
+			ip := net.ParseIP(host).To4()
+			if ip == nil {
+				ip = net.IPv4(127, 0, 0, 1).To4()
+			}
+			s.reply("227 Entering Passive Mode (%d,%d,%d,%d,0,0)", ip[0], ip[1], ip[2], ip[3])
+		case "EPSV":
+			s.reply("229 Entering Extended Passive Mode (|||0|)")
+		case "LIST", "NLST", "RETR", "STOR", "APPE", "MLSD":
+			s.reply("425 Can't open data connection")
+		case "SIZE", "MDTM":
+			s.reply("550 File not available")
+		case "QUIT":
+			s.reply("221 Goodbye")
+			return
+		case "":
+			continue
+		default:
+			s.reply("502 Command not implemented")
+		}
+	}
+}
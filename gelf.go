@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	gelfChunkSize  = 8192 - 12 // 1 パケットからチャンクヘッダー分を引いたもの
+	gelfMaxChunks  = 128
+	gelfQueueSize  = 1000
+	gelfShortLimit = 200
+)
+
+// gelfWriter は新しいエントリを GELF 1.1 で Graylog に送る。
+// UDP は gzip して大きければチャンクに分け、TCP/TLS は NUL 区切りで送る
+type gelfWriter struct {
+	network string // udp, tcp, tls
+	addr    string
+	queue   chan LogEntry
+	conn    net.Conn
+}
+
+// newGELFWriter は udp://host:12201、tcp://host:12201、tls://host:12201 を受け取る
+func newGELFWriter(target string) (*gelfWriter, error) {
+	u, err := url.Parse(target)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid GELF target %q", target)
+	}
+	switch u.Scheme {
+	case "udp", "tcp", "tls":
+	default:
+		return nil, fmt.Errorf("unsupported GELF scheme %q (udp, tcp or tls)", u.Scheme)
+	}
+	return &gelfWriter{network: u.Scheme, addr: u.Host, queue: make(chan LogEntry, gelfQueueSize)}, nil
+}
+
+// add は送信を待たずにキューに積む。溢れた分は捨てる
+func (g *gelfWriter) add(entry LogEntry) {
+	select {
+	case g.queue <- entry:
+	default:
+		fmt.Printf("GELF Error: queue full, dropped entry %d\n", entry.ID)
+	}
+}
+
+func (g *gelfWriter) run() {
+	for entry := range g.queue {
+		msg, err := json.Marshal(gelfMessage(entry))
+		if err != nil {
+			continue
+		}
+		// TCP/TLS は切れていたら 1 度だけ繋ぎ直して送り直す
+		for attempt := 0; attempt < 2; attempt++ {
+			if err = g.send(msg); err == nil {
+				break
+			}
+			if g.conn != nil {
+				g.conn.Close()
+				g.conn = nil
+			}
+		}
+		if err != nil {
+			fmt.Printf("GELF Error: %v\n", err)
+		}
+	}
+}
+
+func (g *gelfWriter) send(msg []byte) error {
+	if g.conn == nil {
+		var err error
+		switch g.network {
+		case "tls":
+			g.conn, err = tls.DialWithDialer(&net.Dialer{Timeout: 10 * time.Second}, "tcp", g.addr, nil)
+		default:
+			g.conn, err = net.DialTimeout(g.network, g.addr, 10*time.Second)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if g.network != "udp" {
+		g.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+		_, err := g.conn.Write(append(msg, 0))
+		return err
+	}
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	gz.Write(msg)
+	gz.Close()
+	for _, packet := range gelfChunks(buf.Bytes()) {
+		if _, err := g.conn.Write(packet); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// gelfChunks は 1 パケットに収まらないメッセージを GELF のチャンクに分ける
+func gelfChunks(data []byte) [][]byte {
+	if len(data) <= gelfChunkSize {
+		return [][]byte{data}
+	}
+	count := (len(data) + gelfChunkSize - 1) / gelfChunkSize
+	if count > gelfMaxChunks {
+		// Graylog は 128 チャンクを超えたメッセージを受け付けないので、入る分だけ送っても意味がない
+		fmt.Printf("GELF Error: message too large (%d bytes)\n", len(data))
+		return nil
+	}
+	id := make([]byte, 8)
+	rand.Read(id)
+	packets := make([][]byte, 0, count)
+	for i := 0; i < count; i++ {
+		chunk := data[i*gelfChunkSize : min((i+1)*gelfChunkSize, len(data))]
+		packet := append([]byte{0x1e, 0x0f}, id...)
+		packet = append(packet, byte(i), byte(count))
+		packets = append(packets, append(packet, chunk...))
+	}
+	return packets
+}
+
+// gelfMessage はエントリを GELF 1.1 のメッセージにする。独自項目は _ で始める
+func gelfMessage(entry LogEntry) map[string]any {
+	short, _, _ := strings.Cut(entry.RawRequest, "\n")
+	short = strings.TrimSpace(short)
+	if len(short) > gelfShortLimit {
+		short = short[:gelfShortLimit]
+	}
+	msg := map[string]any{
+		"version":       "1.1",
+		"host":          domainHost(),
+		"short_message": fmt.Sprintf("%s from %s: %s", entry.Protocol, entry.IP, short),
+		"full_message":  entry.RawRequest,
+		"timestamp":     float64(entry.ID) / float64(time.Second),
+		"level":         5,
+		"_entry_id":     fmt.Sprint(entry.ID),
+		"_protocol":     entry.Protocol,
+		"_src_ip":       entry.IP,
+		"_response":     entry.RawResponse,
+	}
+	if len(entry.Tags) > 0 {
+		msg["_tags"] = strings.Join(entry.Tags, ",")
+	}
+	if req := entryRequest(entry); req != nil {
+		msg["_http_method"] = req.Method
+		msg["_http_url"] = harURL(entry, req)
+		msg["_http_user_agent"] = req.UserAgent()
+	}
+	return msg
+}
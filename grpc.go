@@ -0,0 +1,117 @@
+package main
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative ssrfpb/ssrf.proto
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"go-ssrf-monitor/ssrfpb"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// grpcServer は ssrfpb.Monitor を保存先と hub の上に実装する
+type grpcServer struct {
+	ssrfpb.UnimplementedMonitorServer
+}
+
+func startGRPCServer(port string) {
+	ln, err := net.Listen("tcp", ":"+port)
+	if err != nil {
+		fmt.Printf("gRPC Error: %v\n", err)
+		return
+	}
+	s := grpc.NewServer(grpc.UnaryInterceptor(grpcUnaryAuth), grpc.StreamInterceptor(grpcStreamAuth))
+	ssrfpb.RegisterMonitorServer(s, grpcServer{})
+	if err := s.Serve(ln); err != nil {
+		fmt.Printf("gRPC Error: %v\n", err)
+	}
+}
+
+func (grpcServer) ListLogs(ctx context.Context, req *ssrfpb.ListLogsRequest) (*ssrfpb.ListLogsResponse, error) {
+	filter := logFilter{ip: req.Ip, path: req.Path}
+	if req.SinceUnix > 0 {
+		filter.since = time.Unix(req.SinceUnix, 0)
+	}
+	limit := int(req.Limit)
+	if limit <= 0 {
+		limit = apiDefaultLimit
+	}
+	logs, total, err := queryLogs(filter, max(int(req.Offset), 0), min(limit, apiMaxLimit))
+	if err != nil {
+		return nil, err
+	}
+	resp := &ssrfpb.ListLogsResponse{Total: int32(total)}
+	for _, entry := range logs {
+		resp.Logs = append(resp.Logs, entryToProto(entry))
+	}
+	return resp, nil
+}
+
+func (grpcServer) WatchLogs(req *ssrfpb.WatchLogsRequest, stream grpc.ServerStreamingServer[ssrfpb.LogEntry]) error {
+	ch := hub.subscribe()
+	defer hub.unsubscribe(ch)
+
+	lastID := req.SinceId
+	if lastID > 0 {
+		missed, err := entriesSince(lastID)
+		if err != nil {
+			return err
+		}
+		for _, entry := range missed {
+			if err := stream.Send(entryToProto(entry)); err != nil {
+				return err
+			}
+			lastID = entry.ID
+		}
+	}
+	for {
+		select {
+		case entry := <-ch:
+			if entry.ID <= lastID {
+				continue
+			}
+			if err := stream.Send(entryToProto(entry)); err != nil {
+				return err
+			}
+		case <-stream.Context().Done():
+			return nil
+		}
+	}
+}
+
+func (grpcServer) ClearLogs(ctx context.Context, req *ssrfpb.ClearLogsRequest) (*ssrfpb.ClearLogsResponse, error) {
+	if readOnly {
+		return nil, status.Error(codes.PermissionDenied, "read-only mode")
+	}
+	if err := store.Clear(); err != nil {
+		return nil, err
+	}
+	return &ssrfpb.ClearLogsResponse{}, nil
+}
+
+func entryToProto(entry LogEntry) *ssrfpb.LogEntry {
+	pb := &ssrfpb.LogEntry{
+		Id:           entry.ID,
+		Timestamp:    entry.Timestamp,
+		Protocol:     entry.Protocol,
+		ProtoVersion: entry.ProtoVersion,
+		ParentId:     entry.ParentID,
+		Ip:           entry.IP,
+		RawRequest:   entry.RawRequest,
+		RawResponse:  entry.RawResponse,
+		Tags:         entry.Tags,
+	}
+	if t := entry.TLS; t != nil {
+		pb.Tls = &ssrfpb.TLSInfo{
+			Sni: t.SNI, Version: t.Version, CipherSuite: t.CipherSuite, Alpn: t.ALPN,
+			Ja3: t.JA3, Ja3Hash: t.JA3Hash, Ja3S: t.JA3S, Ja3SHash: t.JA3SHash,
+		}
+	}
+	return pb
+}
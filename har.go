@@ -0,0 +1,181 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+)
+
+// HAR 1.2 のうち、ブラウザの開発者ツールで開くのに必要な項目だけを定義する
+type harLog struct {
+	Version string     `json:"version"`
+	Creator harCreator `json:"creator"`
+	Entries []harEntry `json:"entries"`
+}
+
+type harCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type harEntry struct {
+	StartedDateTime string      `json:"startedDateTime"`
+	Time            int         `json:"time"`
+	Request         harRequest  `json:"request"`
+	Response        harResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         harTimings  `json:"timings"`
+	Comment         string      `json:"comment,omitempty"`
+}
+
+type harRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	QueryString []harNameValue `json:"queryString"`
+	PostData    *harPostData   `json:"postData,omitempty"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type harResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	Content     harContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+type harContent struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+type harTimings struct {
+	Send    int `json:"send"`
+	Wait    int `json:"wait"`
+	Receive int `json:"receive"`
+}
+
+// handleExportHAR は HTTP として読めるエントリを HAR に変換して返す。
+// HTTP 以外の応答しか残っていない場合は 200 とそのテキストで補う
+func handleExportHAR(w http.ResponseWriter, r *http.Request) {
+	logs, err := campaignLogs(r, maxLogs)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	har := harLog{Version: "1.2", Creator: harCreator{Name: "go-ssrf-monitor", Version: "1.0"}, Entries: []harEntry{}}
+	// HAR は古い順に並べる
+	for i := len(logs) - 1; i >= 0; i-- {
+		if entry, ok := harFromEntry(logs[i]); ok {
+			har.Entries = append(har.Entries, entry)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", `attachment; filename="ssrf_logs_`+time.Now().Format("20060102_150405")+`.har"`)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(struct {
+		Log harLog `json:"log"`
+	}{har})
+}
+
+func harFromEntry(entry LogEntry) (harEntry, bool) {
+	req := entryRequest(entry)
+	if req == nil {
+		return harEntry{}, false
+	}
+	body, _ := io.ReadAll(req.Body)
+
+	h := harEntry{
+		StartedDateTime: time.Unix(0, entry.ID).Format(time.RFC3339Nano),
+		Request: harRequest{
+			Method:      req.Method,
+			URL:         harURL(entry, req),
+			HTTPVersion: req.Proto,
+			Cookies:     []harNameValue{},
+			Headers:     harHeaders(req.Header, req.Host),
+			QueryString: []harNameValue{},
+			HeadersSize: -1,
+			BodySize:    len(body),
+		},
+		Comment: entry.Protocol + " from " + entry.IP,
+	}
+	for _, c := range req.Cookies() {
+		h.Request.Cookies = append(h.Request.Cookies, harNameValue{c.Name, c.Value})
+	}
+	for name, values := range req.URL.Query() {
+		for _, v := range values {
+			h.Request.QueryString = append(h.Request.QueryString, harNameValue{name, v})
+		}
+	}
+	if len(body) > 0 {
+		h.Request.PostData = &harPostData{MimeType: req.Header.Get("Content-Type"), Text: string(body)}
+	}
+
+	h.Response = harResponse{
+		Status: http.StatusOK, StatusText: "OK", HTTPVersion: req.Proto,
+		Cookies: []harNameValue{}, Headers: []harNameValue{}, HeadersSize: -1,
+		Content: harContent{Size: len(entry.RawResponse), MimeType: "text/plain", Text: entry.RawResponse},
+	}
+	if res := entryResponse(entry); res != nil {
+		resBody, _ := io.ReadAll(res.Body)
+		h.Response.Status = res.StatusCode
+		h.Response.StatusText = http.StatusText(res.StatusCode)
+		h.Response.HTTPVersion = res.Proto
+		h.Response.Headers = harHeaders(res.Header, "")
+		h.Response.RedirectURL = res.Header.Get("Location")
+		h.Response.BodySize = len(resBody)
+		h.Response.Content = harContent{Size: len(resBody), MimeType: "text/plain", Text: string(resBody)}
+		if ct := res.Header.Get("Content-Type"); ct != "" {
+			h.Response.Content.MimeType = ct
+		}
+	}
+	return h, true
+}
+
+// harURL は絶対 URL を組み立てる。フォワードプロキシ宛てのリクエストは元から絶対 URI
+func harURL(entry LogEntry, req *http.Request) string {
+	if req.URL.IsAbs() {
+		return req.URL.String()
+	}
+	scheme := "http"
+	if entry.Protocol == "https" || entry.Protocol == "wss" || entry.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + req.Host + req.URL.RequestURI()
+}
+
+func harHeaders(header http.Header, host string) []harNameValue {
+	headers := []harNameValue{}
+	if host != "" {
+		headers = append(headers, harNameValue{"Host", host})
+	}
+	for _, name := range sortedHeaderNames(header) {
+		for _, v := range header[name] {
+			headers = append(headers, harNameValue{name, v})
+		}
+	}
+	return headers
+}
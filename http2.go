@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+// newH2CHandler は平文の HTTP/2（prior knowledge と Upgrade: h2c の両方）を受け付けるハンドラーを返す
+func newH2CHandler(h http.Handler) http.Handler {
+	return h2c.NewHandler(h, &http2.Server{})
+}
+
+// dumpHTTP2Request は HTTP/2 のリクエストを疑似ヘッダー付きのテキストに再構成する
+func dumpHTTP2Request(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, ":method: %s\n", r.Method)
+	fmt.Fprintf(&b, ":scheme: %s\n", scheme)
+	fmt.Fprintf(&b, ":authority: %s\n", r.Host)
+	fmt.Fprintf(&b, ":path: %s\n", r.RequestURI)
+
+	names := make([]string, 0, len(r.Header))
+	for name := range r.Header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, v := range r.Header[name] {
+			fmt.Fprintf(&b, "%s: %s\n", strings.ToLower(name), v)
+		}
+	}
+
+	body, _ := io.ReadAll(io.LimitReader(r.Body, maxCaptureSize))
+	if len(body) > 0 {
+		b.WriteString("\n")
+		b.Write(body)
+	}
+	return b.String()
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// entryRequest は HTTP 系のエントリに残した生リクエストを http.Request に戻す。
+// HTTP/2 の疑似ヘッダー形式のダンプにも対応する。HTTP として読めなければ nil を返す
+func entryRequest(entry LogEntry) *http.Request {
+	raw := entry.RawRequest
+	if strings.HasPrefix(raw, ":method: ") {
+		return parseHTTP2Dump(raw)
+	}
+	r, err := http.ReadRequest(bufio.NewReader(strings.NewReader(raw)))
+	if err != nil {
+		return nil
+	}
+	return r
+}
+
+func parseHTTP2Dump(raw string) *http.Request {
+	head, body, _ := strings.Cut(raw, "\n\n")
+	r := &http.Request{Proto: "HTTP/2.0", ProtoMajor: 2, Header: http.Header{}, URL: &url.URL{}}
+	for _, line := range strings.Split(head, "\n") {
+		name, value, ok := strings.Cut(line, ": ")
+		if !ok {
+			continue
+		}
+		switch name {
+		case ":method":
+			r.Method = value
+		case ":scheme":
+			// スキームはエントリの protocol から判断する
+		case ":authority":
+			r.Host = value
+		case ":path":
+			r.RequestURI = value
+			if u, err := url.ParseRequestURI(value); err == nil {
+				r.URL.Path, r.URL.RawQuery = u.Path, u.RawQuery
+			}
+		default:
+			r.Header.Add(name, value)
+		}
+	}
+	r.Body = io.NopCloser(strings.NewReader(body))
+	r.ContentLength = int64(len(body))
+	return r
+}
+
+// withRequestFields は HTTP 系のエントリなら生リクエストからメソッド・Host・パスを取り出して埋める。
+// 取り出せない場合や埋め済みの場合はそのまま返す
+func withRequestFields(entry LogEntry) LogEntry {
+	if entry.Method != "" {
+		return entry
+	}
+	if req := entryRequest(entry); req != nil {
+		entry.Method, entry.Host, entry.Path = req.Method, req.Host, req.URL.Path
+	}
+	return entry
+}
+
+// entryResponse はエントリに残した生レスポンスを http.Response に戻す。読めなければ nil を返す
+func entryResponse(entry LogEntry) *http.Response {
+	if !strings.HasPrefix(entry.RawResponse, "HTTP/") {
+		return nil
+	}
+	res, err := http.ReadResponse(bufio.NewReader(strings.NewReader(entry.RawResponse)), nil)
+	if err != nil {
+		return nil
+	}
+	return res
+}
@@ -0,0 +1,39 @@
+package main
+
+import "sync"
+
+// hubBuffer は購読者ごとに溜めておける件数。読むのが遅い購読者の分は捨てる
+const hubBuffer = 64
+
+// logHub は新しいエントリを待っているクライアント（ロングポーリングなど）に配る
+type logHub struct {
+	mu   sync.Mutex
+	subs map[chan LogEntry]struct{}
+}
+
+var hub = &logHub{subs: map[chan LogEntry]struct{}{}}
+
+func (h *logHub) add(entry LogEntry) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.subs {
+		select {
+		case ch <- entry:
+		default:
+		}
+	}
+}
+
+func (h *logHub) subscribe() chan LogEntry {
+	ch := make(chan LogEntry, hubBuffer)
+	h.mu.Lock()
+	h.subs[ch] = struct{}{}
+	h.mu.Unlock()
+	return ch
+}
+
+func (h *logHub) unsubscribe(ch chan LogEntry) {
+	h.mu.Lock()
+	delete(h.subs, ch)
+	h.mu.Unlock()
+}
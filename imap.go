@@ -0,0 +1,53 @@
+package main
+
+import (
+	"net"
+	"strings"
+)
+
+// handleIMAP は IMAP4rev1 のグリーティングと LOGIN / AUTHENTICATE を受け付け、認証は常に失敗させる
+func handleIMAP(conn net.Conn) {
+	s := newSession(conn)
+	defer s.save("imap")
+
+	s.reply("* OK [CAPABILITY IMAP4rev1 AUTH=PLAIN AUTH=LOGIN] %s IMAP4rev1 ready", domainHost())
+	for {
+		line, err := s.readLine()
+		if err != nil {
+			return
+		}
+		tag, rest, _ := strings.Cut(line, " ")
+		cmd, arg, _ := strings.Cut(rest, " ")
+		if tag == "" {
+			continue
+		}
+		switch strings.ToUpper(cmd) {
+		case "CAPABILITY":
+			s.reply("* CAPABILITY IMAP4rev1 AUTH=PLAIN AUTH=LOGIN")
+			s.reply("%s OK CAPABILITY completed", tag)
+		case "NOOP":
+			s.reply("%s OK NOOP completed", tag)
+		case "LOGIN":
+			s.reply("%s NO [AUTHENTICATIONFAILED] Authentication failed.", tag)
+		case "AUTHENTICATE":
+			// SASL のやり取り（base64）を 1〜2 行受けてから失敗を返す
+			s.reply("+ ")
+			if _, err := s.readLine(); err != nil {
+				return
+			}
+			if strings.EqualFold(strings.TrimSpace(arg), "LOGIN") {
+				s.reply("+ UGFzc3dvcmQ6")
+				if _, err := s.readLine(); err != nil {
+					return
+				}
+			}
+			s.reply("%s NO [AUTHENTICATIONFAILED] Authentication failed.", tag)
+		case "LOGOUT":
+			s.reply("* BYE Logging out")
+			s.reply("%s OK LOGOUT completed", tag)
+			return
+		default:
+			s.reply("%s BAD Command not permitted before authentication", tag)
+		}
+	}
+}
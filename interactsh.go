@@ -0,0 +1,285 @@
+package main
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// interactsh-client の既定の長さ。サブドメインの先頭 20 文字が correlation-id、続く 13 文字が nonce
+const (
+	interactshIDLength    = 20
+	interactshNonceLength = 13
+	interactshMaxQueued   = 1000
+)
+
+// interactshSession は /register で登録されたクライアント。受けたやり取りは AES で暗号化して /poll まで溜めておく
+type interactshSession struct {
+	secret     string
+	aesKey     []byte
+	encodedKey string // クライアントの公開鍵で暗号化した aesKey
+	data       []string
+	lastPoll   time.Time
+}
+
+// interactshServer は interactsh の register / poll / deregister を実装し、記録したエントリを登録済みのクライアントに配る
+type interactshServer struct {
+	mu       sync.Mutex
+	token    string
+	eviction time.Duration
+	sessions map[string]*interactshSession
+}
+
+// interactsh は -interactsh。nil なら無効
+var interactsh *interactshServer
+
+func newInteractshServer(token string, eviction time.Duration) *interactshServer {
+	return &interactshServer{token: token, eviction: eviction, sessions: map[string]*interactshSession{}}
+}
+
+// interaction は interactsh-client が復号して読むやり取り
+type interaction struct {
+	Protocol      string    `json:"protocol"`
+	UniqueID      string    `json:"unique-id"`
+	FullID        string    `json:"full-id"`
+	QType         string    `json:"q-type,omitempty"`
+	RawRequest    string    `json:"raw-request"`
+	RawResponse   string    `json:"raw-response"`
+	RemoteAddress string    `json:"remote-address"`
+	Timestamp     time.Time `json:"timestamp"`
+}
+
+// add は HTTP の Host と DNS の問い合わせ名から登録済みの correlation-id を探し、見つかればそのクライアントに積む
+func (s *interactshServer) add(entry LogEntry) {
+	name := ""
+	switch {
+	case entry.Protocol == "dns":
+		if qname, ok := strings.CutPrefix(entry.RawRequest, "QNAME: "); ok {
+			name, _, _ = strings.Cut(qname, "\n")
+		}
+	case entry.Host != "":
+		name = hostWithoutPort(entry.Host)
+	}
+	name = strings.ToLower(strings.TrimSuffix(name, "."))
+	if name == "" {
+		return
+	}
+	fullID := strings.TrimSuffix(name, "."+strings.ToLower(domainHost()))
+	for _, label := range strings.Split(fullID, ".") {
+		if len(label) < interactshIDLength+interactshNonceLength {
+			continue
+		}
+		s.deliver(label[:interactshIDLength], interactionFromEntry(entry, label, fullID))
+	}
+}
+
+func interactionFromEntry(entry LogEntry, uniqueID, fullID string) interaction {
+	i := interaction{
+		Protocol:      entry.Protocol,
+		UniqueID:      uniqueID,
+		FullID:        fullID,
+		RawRequest:    entry.RawRequest,
+		RawResponse:   entry.RawResponse,
+		RemoteAddress: entry.IP,
+		Timestamp:     time.Unix(0, entry.ID).UTC(),
+	}
+	switch entry.Protocol {
+	case "https", "ws", "wss":
+		i.Protocol = "http"
+	case "dns":
+		if _, rest, ok := strings.Cut(entry.RawRequest, "QTYPE: "); ok {
+			i.QType, _, _ = strings.Cut(rest, "\n")
+		}
+	}
+	return i
+}
+
+func (s *interactshServer) deliver(id string, i interaction) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sess, ok := s.sessions[id]
+	if !ok {
+		return
+	}
+	data, _ := json.Marshal(i)
+	encrypted, err := interactshEncrypt(sess.aesKey, data)
+	if err != nil {
+		return
+	}
+	// poll されないまま溜まり続けないよう古いものから捨てる
+	if len(sess.data) >= interactshMaxQueued {
+		sess.data = sess.data[1:]
+	}
+	sess.data = append(sess.data, encrypted)
+}
+
+// interactshEncrypt は interactsh と同じく先頭 16 バイトを IV にした AES-256-CFB で暗号化して base64 にする
+func interactshEncrypt(key, plaintext []byte) (string, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", err
+	}
+	out := make([]byte, aes.BlockSize+len(plaintext))
+	iv := out[:aes.BlockSize]
+	rand.Read(iv)
+	cipher.NewCFBEncrypter(block, iv).XORKeyStream(out[aes.BlockSize:], plaintext)
+	return base64.StdEncoding.EncodeToString(out), nil
+}
+
+// authorized は -interactsh-token があれば Authorization ヘッダーがそれと一致するかを確かめる
+func (s *interactshServer) authorized(w http.ResponseWriter, r *http.Request) bool {
+	if s.token == "" || subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte(s.token)) == 1 {
+		return true
+	}
+	interactshError(w, http.StatusUnauthorized, "invalid token")
+	return false
+}
+
+func interactshError(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": msg})
+}
+
+// evict は eviction の間 poll されていないクライアントを消す。呼び出し側でロックを取っておく
+func (s *interactshServer) evict(now time.Time) {
+	for id, sess := range s.sessions {
+		if now.Sub(sess.lastPoll) > s.eviction {
+			delete(s.sessions, id)
+		}
+	}
+}
+
+// handleRegister は POST /register で {"public-key", "secret-key", "correlation-id"} を登録する
+func (s *interactshServer) handleRegister(w http.ResponseWriter, r *http.Request) {
+	if !s.authorized(w, r) {
+		return
+	}
+	var body struct {
+		PublicKey     string `json:"public-key"`
+		SecretKey     string `json:"secret-key"`
+		CorrelationID string `json:"correlation-id"`
+	}
+	if err := json.NewDecoder(io.LimitReader(r.Body, 1<<16)).Decode(&body); err != nil {
+		interactshError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	id := strings.ToLower(body.CorrelationID)
+	if len(id) != interactshIDLength || body.SecretKey == "" {
+		interactshError(w, http.StatusBadRequest, "invalid correlation-id or secret-key")
+		return
+	}
+	pub, err := parseInteractshKey(body.PublicKey)
+	if err != nil {
+		interactshError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	aesKey := make([]byte, 32)
+	rand.Read(aesKey)
+	encryptedKey, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, pub, aesKey, nil)
+	if err != nil {
+		interactshError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.evict(now)
+	// 同じ secret-key なら登録し直し（セッションの再開）を許す
+	if old, ok := s.sessions[id]; ok && subtle.ConstantTimeCompare([]byte(old.secret), []byte(body.SecretKey)) != 1 {
+		interactshError(w, http.StatusBadRequest, "correlation-id provided already exists")
+		return
+	}
+	s.sessions[id] = &interactshSession{
+		secret:     body.SecretKey,
+		aesKey:     aesKey,
+		encodedKey: base64.StdEncoding.EncodeToString(encryptedKey),
+		lastPoll:   now,
+	}
+	writeJSON(w, map[string]string{"message": "registration successful"})
+}
+
+// parseInteractshKey は base64 で包んだ PEM の RSA 公開鍵を読む
+func parseInteractshKey(s string) (*rsa.PublicKey, error) {
+	decoded, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(decoded)
+	if block == nil {
+		return nil, errors.New("invalid public-key")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	pub, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("public-key must be RSA")
+	}
+	return pub, nil
+}
+
+// handlePoll は GET /poll?id=&secret= で溜まったやり取りを返して空にする
+func (s *interactshServer) handlePoll(w http.ResponseWriter, r *http.Request) {
+	if !s.authorized(w, r) {
+		return
+	}
+	id := strings.ToLower(r.URL.Query().Get("id"))
+	s.mu.Lock()
+	sess, ok := s.sessions[id]
+	if !ok || subtle.ConstantTimeCompare([]byte(sess.secret), []byte(r.URL.Query().Get("secret"))) != 1 {
+		s.mu.Unlock()
+		interactshError(w, http.StatusBadRequest, "could not get interactions: correlation-id or secret-key is invalid")
+		return
+	}
+	data := sess.data
+	sess.data = nil
+	sess.lastPoll = time.Now()
+	key := sess.encodedKey
+	s.mu.Unlock()
+
+	if data == nil {
+		data = []string{}
+	}
+	writeJSON(w, map[string]any{"data": data, "extra": nil, "aes_key": key, "tld_data": nil})
+}
+
+// handleDeregister は POST /deregister で {"correlation-id", "secret-key"} の登録を消す
+func (s *interactshServer) handleDeregister(w http.ResponseWriter, r *http.Request) {
+	if !s.authorized(w, r) {
+		return
+	}
+	var body struct {
+		CorrelationID string `json:"correlation-id"`
+		SecretKey     string `json:"secret-key"`
+	}
+	if err := json.NewDecoder(io.LimitReader(r.Body, 4096)).Decode(&body); err != nil {
+		interactshError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	id := strings.ToLower(body.CorrelationID)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sess, ok := s.sessions[id]
+	if !ok || subtle.ConstantTimeCompare([]byte(sess.secret), []byte(body.SecretKey)) != 1 {
+		interactshError(w, http.StatusBadRequest, "correlation-id or secret-key is invalid")
+		return
+	}
+	delete(s.sessions, id)
+	writeJSON(w, map[string]string{"message": "deregistration successful"})
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// ipForms は IPv4 アドレスの別表記を並べる。多くの URL パーサーや inet_aton が同じアドレスとして解釈する
+func ipForms(ip net.IP) []payload {
+	ip = ip.To4()
+	a, b, c, d := uint32(ip[0]), uint32(ip[1]), uint32(ip[2]), uint32(ip[3])
+	n := a<<24 | b<<16 | c<<8 | d
+	return []payload{
+		{"10 進（ドット付き）", ip.String()},
+		{"10 進（ドットなし）", fmt.Sprintf("%d", n)},
+		{"16 進（ドットなし）", fmt.Sprintf("0x%x", n)},
+		{"8 進（ドットなし）", fmt.Sprintf("0%o", n)},
+		{"16 進（ドット付き）", fmt.Sprintf("0x%x.0x%x.0x%x.0x%x", a, b, c, d)},
+		{"8 進（ドット付き）", fmt.Sprintf("0%o.0%o.0%o.0%o", a, b, c, d)},
+		{"8 進（0 埋め）", fmt.Sprintf("%08o.%08o.%08o.%08o", a, b, c, d)},
+		{"3 要素", fmt.Sprintf("%d.%d.%d", a, b, c<<8|d)},
+		{"2 要素", fmt.Sprintf("%d.%d", a, b<<16|c<<8|d)},
+		{"混在（16 進.8 進.10 進）", fmt.Sprintf("0x%x.0%o.%d", a, b, c<<8|d)},
+		{"混在（8 進.16 進）", fmt.Sprintf("0%o.0x%x", a, b<<16|c<<8|d)},
+		{"IPv4 射影 IPv6", "::ffff:" + ip.String()},
+		{"IPv4 射影 IPv6（16 進）", fmt.Sprintf("::ffff:%x:%x", n>>16, n&0xffff)},
+		{"IPv4 射影 IPv6（省略なし）", "0:0:0:0:0:ffff:" + ip.String()},
+		{"IPv4 互換 IPv6", "::" + ip.String()},
+	}
+}
+
+// urlHost は URL のホスト部に入れられる形にする（IPv6 は角括弧で囲む）
+func urlHost(host string) string {
+	if strings.Contains(host, ":") {
+		return "[" + host + "]"
+	}
+	return host
+}
+
+// handleIPForms は GET /api/ip でこのサーバーの IPv4 アドレスの別表記を返す。?ip= で任意のアドレスも変換できる
+func handleIPForms(w http.ResponseWriter, r *http.Request) {
+	ip := serverIPv4()
+	if s := r.URL.Query().Get("ip"); s != "" {
+		ip = net.ParseIP(s).To4()
+		if ip == nil {
+			http.Error(w, "invalid ipv4 address", http.StatusBadRequest)
+			return
+		}
+	}
+	if ip == nil {
+		http.Error(w, "server address unknown; set -dns-ip", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, map[string]any{"ip": ip.String(), "forms": ipForms(ip)})
+}
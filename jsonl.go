@@ -0,0 +1,355 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"slices"
+	"sync"
+	"time"
+)
+
+// jsonlStore はエントリを 1 行 1 JSON で追記するだけのファイル。データベースなしで再起動に耐える。
+// 削除は残す行だけでファイルを書き直す。maxSize を超えたら path.1.gz, path.2.gz ... にローテートする
+type jsonlStore struct {
+	mu   sync.Mutex
+	path string
+	file *os.File
+	size int64
+
+	maxSize  int64 // 0 ならローテートしない
+	maxFiles int   // 残す圧縮済みファイルの数
+}
+
+func openJSONLStore(path string, maxSize int64, maxFiles int) (*jsonlStore, error) {
+	s := &jsonlStore{path: path, maxSize: maxSize, maxFiles: maxFiles}
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *jsonlStore) open() error {
+	f, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	s.file, s.size = f, info.Size()
+	return nil
+}
+
+func (s *jsonlStore) Append(entry LogEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n, err := s.file.Write(append(data, '\n'))
+	s.size += int64(n)
+	if err != nil {
+		return err
+	}
+	if s.maxSize > 0 && s.size >= s.maxSize {
+		return s.rotate()
+	}
+	return nil
+}
+
+func (s *jsonlStore) rotatedName(i int) string {
+	return fmt.Sprintf("%s.%d.gz", s.path, i)
+}
+
+// rotate は現在のファイルを gzip して path.1.gz にし、古いものを 1 つずつずらす。maxFiles を超えた分は消す
+func (s *jsonlStore) rotate() error {
+	s.file.Close()
+	os.Remove(s.rotatedName(s.maxFiles))
+	for i := s.maxFiles - 1; i >= 1; i-- {
+		os.Rename(s.rotatedName(i), s.rotatedName(i+1))
+	}
+	if s.maxFiles > 0 {
+		if err := gzipFile(s.path, s.rotatedName(1)); err != nil {
+			s.open()
+			return err
+		}
+	}
+	os.Remove(s.path)
+	return s.open()
+}
+
+func gzipFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(out)
+	if _, err := io.Copy(gz, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// readLines は現在のファイルから、足りなければローテート済みのファイルへと遡って読み、
+// 空でない行を古い順に返す。limit が正なら新しい方から limit 行だけ返す
+func (s *jsonlStore) readLines(limit int) ([][]byte, error) {
+	f, err := os.Open(s.path)
+	if err != nil {
+		return nil, err
+	}
+	lines, err := readFileLines(f, limit)
+	f.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	for i := 1; i <= s.maxFiles && (limit <= 0 || len(lines) < limit); i++ {
+		f, err := os.Open(s.rotatedName(i))
+		if err != nil {
+			break
+		}
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			f.Close()
+			break
+		}
+		remaining := 0
+		if limit > 0 {
+			remaining = limit - len(lines)
+		}
+		older, err := readFileLines(gz, remaining)
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+		lines = append(older, lines...)
+	}
+	return lines, nil
+}
+
+// readFileLines は空でない行を古い順に返す。limit が正なら末尾の limit 行だけ残す
+func readFileLines(f io.Reader, limit int) ([][]byte, error) {
+	var lines [][]byte
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadBytes('\n')
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			lines = append(lines, line)
+			if limit > 0 && len(lines) > limit {
+				lines = lines[1:]
+			}
+		}
+		if err == io.EOF {
+			return lines, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// List は末尾の limit 行を新しい順に返す
+func (s *jsonlStore) List(limit int) ([]LogEntry, error) {
+	return s.Page(0, limit)
+}
+
+// Page は末尾の offset+limit 行だけを読む
+func (s *jsonlStore) Page(offset, limit int) ([]LogEntry, error) {
+	offset = max(offset, 0)
+	n := 0
+	if limit > 0 {
+		n = offset + min(limit, math.MaxInt-offset)
+	}
+	s.mu.Lock()
+	lines, err := s.readLines(n)
+	s.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	return parseLines(lines[:max(len(lines)-offset, 0)], math.MinInt64), nil
+}
+
+// Since は末尾から id 以下の行に当たるまで読む
+func (s *jsonlStore) Since(id int64, limit int) ([]LogEntry, error) {
+	s.mu.Lock()
+	lines, err := s.readLines(0)
+	s.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	logs := parseLines(lines, id)
+	if limit > 0 && limit < len(logs) {
+		logs = logs[len(logs)-limit:]
+	}
+	slices.Reverse(logs)
+	return logs, nil
+}
+
+func (s *jsonlStore) Count() (int, error) {
+	s.mu.Lock()
+	lines, err := s.readLines(0)
+	s.mu.Unlock()
+	return len(lines), err
+}
+
+// Each は全てのファイルを一度だけ読み、新しい順に渡す。状態の絞り込みも Each で答える
+func (s *jsonlStore) Each(fn func(LogEntry) bool) error {
+	s.mu.Lock()
+	lines, err := s.readLines(0)
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	for i := len(lines) - 1; i >= 0; i-- {
+		var entry LogEntry
+		if err := json.Unmarshal(lines[i], &entry); err != nil {
+			fmt.Printf("Logfile Error: skipping broken line: %v\n", err)
+			continue
+		}
+		if !fn(entry) {
+			break
+		}
+	}
+	return nil
+}
+
+func (s *jsonlStore) CountState(f stateFilter) (int, error) {
+	return countState(s, f)
+}
+
+func (s *jsonlStore) PageState(f stateFilter, offset, limit int) ([]LogEntry, error) {
+	return pageState(s, f, offset, limit)
+}
+
+// parseLines は古い順の行を新しい順のエントリにする。ID が after 以下の行に当たったらそこで止める
+func parseLines(lines [][]byte, after int64) []LogEntry {
+	logs := make([]LogEntry, 0, len(lines))
+	for i := len(lines) - 1; i >= 0; i-- {
+		var entry LogEntry
+		if err := json.Unmarshal(lines[i], &entry); err != nil {
+			fmt.Printf("Logfile Error: skipping broken line: %v\n", err)
+			continue
+		}
+		if entry.ID <= after {
+			break
+		}
+		logs = append(logs, entry)
+	}
+	return logs
+}
+
+func (s *jsonlStore) Get(id int64) (LogEntry, bool, error) {
+	logs, err := s.List(0)
+	if err != nil {
+		return LogEntry{}, false, err
+	}
+	for _, entry := range logs {
+		if entry.ID == id {
+			return entry, true, nil
+		}
+	}
+	return LogEntry{}, false, nil
+}
+
+// rewrite は drop が true を返した行を除いてファイルを書き直し、除いた行数を返す。
+// drop には新しい方から数えた位置も渡す
+func (s *jsonlStore) rewrite(drop func(entry LogEntry, i int) bool) (int, error) {
+	return s.edit(func(entry LogEntry, i int) ([]byte, bool) {
+		return nil, drop(entry, i)
+	})
+}
+
+// edit は fn が true を返した行を、返した内容（nil なら行ごと削除）に差し替えてファイルを書き直し、
+// 差し替えた行数を返す
+func (s *jsonlStore) edit(fn func(entry LogEntry, i int) ([]byte, bool)) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f, err := os.Open(s.path)
+	if err != nil {
+		return 0, err
+	}
+	lines, err := readFileLines(f, 0)
+	f.Close()
+	if err != nil {
+		return 0, err
+	}
+
+	var kept bytes.Buffer
+	n := 0
+	for i, line := range lines {
+		var entry LogEntry
+		if json.Unmarshal(line, &entry) == nil {
+			if replacement, ok := fn(entry, len(lines)-1-i); ok {
+				n++
+				line = replacement
+			}
+		}
+		if line == nil {
+			continue
+		}
+		kept.Write(line)
+		kept.WriteByte('\n')
+	}
+	if n == 0 {
+		return 0, nil
+	}
+
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, kept.Bytes(), 0600); err != nil {
+		return 0, err
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return 0, err
+	}
+	s.file.Close()
+	return n, s.open()
+}
+
+// Update は現在のファイルだけを書き直す。ローテート済みのエントリは変更しない
+func (s *jsonlStore) Update(entry LogEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	_, err = s.edit(func(e LogEntry, _ int) ([]byte, bool) { return data, e.ID == entry.ID })
+	return err
+}
+
+func (s *jsonlStore) Delete(id int64) error {
+	_, err := s.rewrite(func(entry LogEntry, _ int) bool { return entry.ID == id })
+	return err
+}
+
+// Clear はローテート済みのファイルも消す
+func (s *jsonlStore) Clear() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := 1; i <= s.maxFiles; i++ {
+		os.Remove(s.rotatedName(i))
+	}
+	s.size = 0
+	return s.file.Truncate(0)
+}
+
+func (s *jsonlStore) Prune(keep int, before time.Time) (int, error) {
+	return s.rewrite(func(entry LogEntry, i int) bool { return pruned(entry, i, keep, before) })
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"text/template"
+)
+
+// k8sTemplates は kube-apiserver を模した応答のテンプレート
+var k8sTemplates = template.Must(template.New("k8s").Parse(`
+{{define "version"}}{
+  "major": "1",
+  "minor": "28",
+  "gitVersion": "v1.28.4",
+  "gitCommit": "bae2c62678db2b5053817bc97181fcc2e8388103",
+  "gitTreeState": "clean",
+  "buildDate": "2023-11-15T16:48:54Z",
+  "goVersion": "go1.20.11",
+  "compiler": "gc",
+  "platform": "linux/amd64"
+}{{end}}
+{{define "api"}}{
+  "kind": "APIVersions",
+  "versions": ["v1"],
+  "serverAddressByClientCIDRs": [{"clientCIDR": "0.0.0.0/0", "serverAddress": "{{.ServerAddress}}"}]
+}{{end}}
+{{define "apis"}}{
+  "kind": "APIGroupList",
+  "apiVersion": "v1",
+  "groups": [
+    {"name": "apps", "versions": [{"groupVersion": "apps/v1", "version": "v1"}], "preferredVersion": {"groupVersion": "apps/v1", "version": "v1"}},
+    {"name": "batch", "versions": [{"groupVersion": "batch/v1", "version": "v1"}], "preferredVersion": {"groupVersion": "batch/v1", "version": "v1"}},
+    {"name": "rbac.authorization.k8s.io", "versions": [{"groupVersion": "rbac.authorization.k8s.io/v1", "version": "v1"}], "preferredVersion": {"groupVersion": "rbac.authorization.k8s.io/v1", "version": "v1"}}
+  ]
+}{{end}}
+{{define "unauthorized"}}{
+  "kind": "Status",
+  "apiVersion": "v1",
+  "metadata": {},
+  "status": "Failure",
+  "message": "Unauthorized",
+  "reason": "Unauthorized",
+  "code": 401
+}{{end}}
+`))
+
+// k8sEnabled は -k8s。/api/v1 以下を管理用の API ではなくエミュレーターに回す
+var k8sEnabled bool
+
+// emulateKubernetesAPI は /version, /api, /apis に応答し、それ以外の API パスには 401 を返す。
+// 付与された Bearer トークンはタグにも残す
+func emulateKubernetesAPI(r *http.Request) *mockResponse {
+	path := strings.TrimSuffix(r.URL.Path, "/")
+	name := "unauthorized"
+	status := http.StatusUnauthorized
+	switch {
+	case path == "/version" || path == "/api" || path == "/apis":
+		name, status = strings.TrimPrefix(path, "/"), http.StatusOK
+	case path == "/healthz" || path == "/livez" || path == "/readyz":
+		return textResponse(http.StatusOK, "ok", "k8s-api")
+	case strings.HasPrefix(path, "/api/") || strings.HasPrefix(path, "/apis/") || strings.HasPrefix(path, "/openapi"):
+		// リソースへのアクセスは全て 401
+	default:
+		return nil
+	}
+
+	var body strings.Builder
+	k8sTemplates.ExecuteTemplate(&body, name, struct{ ServerAddress string }{domainHost() + ":6443"})
+	res := jsonResponse(status, body.String(), k8sTokenTags(r)...)
+	res.Header.Set("Audit-Id", randomString(8, "0123456789abcdef")+"-"+randomString(4, "0123456789abcdef"))
+	res.Header.Set("Cache-Control", "no-cache, private")
+	return res
+}
+
+// k8sClient は kubectl や client-go からのリクエストらしいかを返す。-docker と両方で /version を取り合うときに使う
+func k8sClient(r *http.Request) bool {
+	ua := r.Header.Get("User-Agent")
+	return strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ") ||
+		strings.HasPrefix(ua, "kubectl/") || strings.Contains(ua, "kubernetes/")
+}
+
+// k8sTokenTags は Bearer トークンが付いていればタグを付け、サービスアカウントの JWT なら sub も添える
+func k8sTokenTags(r *http.Request) []string {
+	tags := []string{"k8s-api"}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		return tags
+	}
+	tags = append(tags, "bearer-token")
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return tags
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return tags
+	}
+	var claims struct {
+		Sub string `json:"sub"`
+	}
+	if json.Unmarshal(payload, &claims) == nil && claims.Sub != "" {
+		tags = append(tags, "sub="+claims.Sub)
+	}
+	return tags
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"bufio"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+)
+
+const (
+	ldapBindRequest      = 0x60
+	ldapBindResponse     = 0x61
+	ldapUnbindRequest    = 0x42
+	ldapSearchRequest    = 0x63
+	ldapSearchResultDone = 0x65
+
+	ldapResultSuccess  = 0
+	ldapResultReferral = 10
+)
+
+var ldapScopes = map[int]string{0: "baseObject", 1: "singleLevel", 2: "wholeSubtree"}
+
+// handleLDAP は bind と search を記録する LDAP サーバーとして振る舞う。
+// referral が true の場合、search に対して HTTP リスナーへの referral を返す
+func handleLDAP(conn net.Conn, referral bool) {
+	s := newSession(conn)
+	defer s.save("ldap")
+
+	for {
+		raw, msg, err := readBER(s.reader)
+		if err != nil {
+			return
+		}
+		id, op, err := parseLDAPMessage(msg)
+		if err != nil {
+			s.record(&s.request, fmt.Sprintf("Malformed LDAP message: %v\n%s\n", err, hex.EncodeToString(raw)))
+			return
+		}
+
+		switch op.tag {
+		case ldapBindRequest:
+			version, name, password := parseLDAPBind(op.value)
+			s.record(&s.request, fmt.Sprintf("BindRequest (id=%d) version=%d name=%q password=%q\nraw: %s\n",
+				id, version, name, password, hex.EncodeToString(raw)))
+			s.sendLDAP(id, ldapBindResponse, ldapResultSuccess, "")
+		case ldapSearchRequest:
+			base, scope := parseLDAPSearch(op.value)
+			s.record(&s.request, fmt.Sprintf("SearchRequest (id=%d) base=%q scope=%s\n", id, base, ldapScopes[scope]))
+			if referral {
+				ref := fmt.Sprintf("http://%s/ldap/%s", serverDomain, url.PathEscape(base))
+				s.sendLDAP(id, ldapSearchResultDone, ldapResultReferral, ref)
+			} else {
+				s.sendLDAP(id, ldapSearchResultDone, ldapResultSuccess, "")
+			}
+		case ldapUnbindRequest:
+			s.record(&s.request, fmt.Sprintf("UnbindRequest (id=%d)\n", id))
+			return
+		default:
+			s.record(&s.request, fmt.Sprintf("Unsupported operation 0x%02x (id=%d)\nraw: %s\n", op.tag, id, hex.EncodeToString(raw)))
+		}
+	}
+}
+
+// sendLDAP は LDAPResult 形式の応答を送信し、内容を記録する
+func (s *session) sendLDAP(id int, opTag byte, code int, referralURL string) {
+	result := berEncode(0x0A, []byte{byte(code)})
+	result = append(result, berEncode(0x04, nil)...)
+	result = append(result, berEncode(0x04, nil)...)
+	if referralURL != "" {
+		result = append(result, berEncode(0xA3, berEncode(0x04, []byte(referralURL)))...)
+	}
+	msg := append(berEncode(0x02, berInt(id)), berEncode(opTag, result)...)
+	s.conn.Write(berEncode(0x30, msg))
+
+	name := "BindResponse"
+	if opTag == ldapSearchResultDone {
+		name = "SearchResultDone"
+	}
+	line := fmt.Sprintf("%s (id=%d) resultCode=%d", name, id, code)
+	if referralURL != "" {
+		line += " referral=" + referralURL
+	}
+	s.record(&s.response, line+"\n")
+}
+
+type berElement struct {
+	tag   byte
+	value []byte
+}
+
+// readBER は BER の TLV を 1 つ読み、TLV 全体と値部分を返す
+func readBER(r *bufio.Reader) ([]byte, []byte, error) {
+	tag, err := r.ReadByte()
+	if err != nil {
+		return nil, nil, err
+	}
+	first, err := r.ReadByte()
+	if err != nil {
+		return nil, nil, err
+	}
+	header := []byte{tag, first}
+	length := int(first)
+	if first&0x80 != 0 {
+		n := int(first & 0x7f)
+		if n == 0 || n > 4 {
+			return nil, nil, errors.New("unsupported length encoding")
+		}
+		lenBytes := make([]byte, n)
+		if _, err := io.ReadFull(r, lenBytes); err != nil {
+			return nil, nil, err
+		}
+		header = append(header, lenBytes...)
+		length = 0
+		for _, b := range lenBytes {
+			length = length<<8 | int(b)
+		}
+	}
+	if length > maxCaptureSize {
+		return nil, nil, errors.New("message too large")
+	}
+	value := make([]byte, length)
+	if _, err := io.ReadFull(r, value); err != nil {
+		return nil, nil, err
+	}
+	return append(header, value...), value, nil
+}
+
+// berNext はバッファ先頭の TLV を取り出し、残りを返す
+func berNext(b []byte) (berElement, []byte, error) {
+	if len(b) < 2 {
+		return berElement{}, nil, errors.New("truncated element")
+	}
+	tag, length, off := b[0], int(b[1]), 2
+	if b[1]&0x80 != 0 {
+		n := int(b[1] & 0x7f)
+		if n == 0 || n > 4 || len(b) < 2+n {
+			return berElement{}, nil, errors.New("bad length")
+		}
+		length = 0
+		for _, c := range b[2 : 2+n] {
+			length = length<<8 | int(c)
+		}
+		off += n
+	}
+	if length < 0 || off+length > len(b) {
+		return berElement{}, nil, errors.New("truncated value")
+	}
+	return berElement{tag: tag, value: b[off : off+length]}, b[off+length:], nil
+}
+
+func berEncode(tag byte, value []byte) []byte {
+	n := len(value)
+	var out []byte
+	switch {
+	case n < 0x80:
+		out = []byte{tag, byte(n)}
+	case n < 0x100:
+		out = []byte{tag, 0x81, byte(n)}
+	case n < 0x10000:
+		out = []byte{tag, 0x82, byte(n >> 8), byte(n)}
+	default:
+		out = []byte{tag, 0x84, byte(n >> 24), byte(n >> 16), byte(n >> 8), byte(n)}
+	}
+	return append(out, value...)
+}
+
+func berInt(v int) []byte {
+	var out []byte
+	for {
+		out = append([]byte{byte(v)}, out...)
+		v >>= 8
+		if v == 0 {
+			break
+		}
+	}
+	if out[0]&0x80 != 0 {
+		out = append([]byte{0}, out...)
+	}
+	return out
+}
+
+func berParseInt(b []byte) int {
+	v := 0
+	for _, c := range b {
+		v = v<<8 | int(c)
+	}
+	return v
+}
+
+func parseLDAPMessage(msg []byte) (int, berElement, error) {
+	idElem, rest, err := berNext(msg)
+	if err != nil {
+		return 0, berElement{}, err
+	}
+	if idElem.tag != 0x02 {
+		return 0, berElement{}, errors.New("missing messageID")
+	}
+	op, _, err := berNext(rest)
+	if err != nil {
+		return 0, berElement{}, err
+	}
+	return berParseInt(idElem.value), op, nil
+}
+
+func parseLDAPBind(b []byte) (version int, name, password string) {
+	elem, rest, err := berNext(b)
+	if err != nil {
+		return
+	}
+	version = berParseInt(elem.value)
+	if elem, rest, err = berNext(rest); err != nil {
+		return
+	}
+	name = string(elem.value)
+	if elem, _, err = berNext(rest); err != nil {
+		return
+	}
+	if elem.tag == 0x80 {
+		password = string(elem.value)
+	} else {
+		password = fmt.Sprintf("(SASL %x)", elem.value)
+	}
+	return
+}
+
+func parseLDAPSearch(b []byte) (base string, scope int) {
+	elem, rest, err := berNext(b)
+	if err != nil {
+		return
+	}
+	base = string(elem.value)
+	if elem, _, err = berNext(rest); err != nil {
+		return
+	}
+	scope = berParseInt(elem.value)
+	return
+}
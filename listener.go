@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bufio"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	connTimeout    = 60 * time.Second
+	maxLineLength  = 8192
+	maxCaptureSize = 1 << 20
+)
+
+// listenTCP は指定ポートで接続を受け付け、接続ごとに handle を goroutine で実行する
+func listenTCP(name, port string, handle func(net.Conn)) {
+	ln, err := newListener(":" + port)
+	if err != nil {
+		fmt.Printf("%s Error: %v\n", name, err)
+		return
+	}
+	// 受け付けに失敗したら net/http と同じく 5ms から 1s まで倍々に待ってやり直す。
+	// ファイルディスクリプタが尽きたときなどに空回りしてログを埋めないため
+	var delay time.Duration
+	for {
+		conn, err := ln.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			return
+		}
+		if err != nil {
+			delay = min(max(delay*2, 5*time.Millisecond), time.Second)
+			fmt.Printf("%s Error: %v; retrying in %v\n", name, err, delay)
+			time.Sleep(delay)
+			continue
+		}
+		delay = 0
+		go func() {
+			defer conn.Close()
+			conn.SetDeadline(time.Now().Add(connTimeout))
+			handle(conn)
+		}()
+	}
+}
+
+// splitList は "25,587" のようなカンマ区切りの指定を分解する
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// dumpBytes は UTF-8 として読めるならそのまま、読めなければ hex ダンプにして返す
+func dumpBytes(data []byte) string {
+	if utf8.Valid(data) {
+		return string(data)
+	}
+	return hex.Dump(data)
+}
+
+func remoteIP(conn net.Conn) string {
+	ip, _, _ := net.SplitHostPort(conn.RemoteAddr().String())
+	return ip
+}
+
+// session は行ベースのプロトコルで、クライアントの送信内容とサーバーの応答を別々に記録する
+type session struct {
+	conn     net.Conn
+	reader   *bufio.Reader
+	request  strings.Builder
+	response strings.Builder
+}
+
+func newSession(conn net.Conn) *session {
+	return &session{conn: conn, reader: bufio.NewReaderSize(conn, maxLineLength)}
+}
+
+// readLine は 1 行読み、改行を除いて返す。読んだ内容はそのまま記録する
+func (s *session) readLine() (string, error) {
+	line, err := s.reader.ReadString('\n')
+	if line != "" {
+		s.record(&s.request, line)
+	}
+	if err != nil && line == "" {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// reply は応答を CRLF 付きで送信する
+func (s *session) reply(format string, args ...any) {
+	line := fmt.Sprintf(format, args...) + "\r\n"
+	s.record(&s.response, line)
+	io.WriteString(s.conn, line)
+}
+
+func (s *session) record(b *strings.Builder, data string) {
+	if b.Len()+len(data) > maxCaptureSize {
+		return
+	}
+	b.WriteString(data)
+}
+
+// save は接続中のやり取りを 1 件のログとして保存する
+func (s *session) save(protocol string) {
+	addLog(newLogEntry(protocol, remoteIP(s.conn), s.request.String(), s.response.String()))
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// mailDigestMax は 1 通のメールに詳細を並べる最大件数
+const mailDigestMax = 50
+
+// mailAlerter は最初のエントリから digest の間に届いた分をまとめて 1 通のメールで知らせる。
+// スキャナーが大量に叩いてもメールは digest ごとに 1 通になる
+type mailAlerter struct {
+	mu      sync.Mutex
+	pending []LogEntry
+	addr    string
+	from    string
+	to      []string
+	auth    smtp.Auth
+	digest  time.Duration
+	tmpl    *template.Template // 1 エントリ分の行。nil なら既定の書式
+}
+
+func newMailAlerter(addr string, to []string, from, user, password string, digest time.Duration, tmpl *template.Template) (*mailAlerter, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, fmt.Errorf("-alert-smtp must be host:port: %v", err)
+	}
+	if len(to) == 0 {
+		return nil, fmt.Errorf("-alert-to is required")
+	}
+	a := &mailAlerter{addr: addr, from: from, to: to, digest: digest, tmpl: tmpl}
+	if user != "" {
+		// PlainAuth は TLS か localhost でないと認証情報を送らない
+		a.auth = smtp.PlainAuth("", user, password, host)
+	}
+	return a, nil
+}
+
+func (a *mailAlerter) add(entry LogEntry) {
+	if notifyMatch(entry) {
+		a.requeue(entry)
+	}
+}
+
+func (a *mailAlerter) flush() {
+	a.mu.Lock()
+	batch := a.pending
+	a.pending = nil
+	a.mu.Unlock()
+	if len(batch) == 0 {
+		return
+	}
+	msg := a.message(batch)
+	err := withRetry(func() (bool, error) { return true, smtp.SendMail(a.addr, a.auth, a.sender(), a.to, msg) })
+	if err != nil {
+		for _, entry := range batch {
+			deadLetters.park("Mail", entry, err, a.requeue)
+		}
+	}
+}
+
+// requeue はデッドレターから戻されたエントリを次のダイジェストに入れる
+func (a *mailAlerter) requeue(entry LogEntry) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.pending = append(a.pending, entry)
+	if len(a.pending) == 1 {
+		time.AfterFunc(a.digest, a.flush)
+	}
+}
+
+// sender は -alert-from が空なら ssrf-monitor@<ドメイン> を使う
+func (a *mailAlerter) sender() string {
+	if a.from == "" {
+		return "ssrf-monitor@" + domainHost()
+	}
+	return a.from
+}
+
+func (a *mailAlerter) message(batch []LogEntry) []byte {
+	ips := map[string]bool{}
+	for _, entry := range batch {
+		ips[entry.IP] = true
+	}
+
+	var body strings.Builder
+	fmt.Fprintf(&body, "%s に %d 件の新しいリクエストがありました（送信元 %d 件）。\n", serverDomain, len(batch), len(ips))
+	fmt.Fprintf(&body, "管理画面: http://%s/admin\n\n", serverDomain)
+	for i, entry := range batch {
+		if i == mailDigestMax {
+			fmt.Fprintf(&body, "... 他 %d 件\n", len(batch)-mailDigestMax)
+			break
+		}
+		if a.tmpl != nil {
+			text, err := renderNotify(a.tmpl, entry)
+			if err != nil {
+				text = "テンプレートエラー: " + err.Error()
+			}
+			body.WriteString(strings.TrimRight(text, "\n") + "\n")
+			continue
+		}
+		fmt.Fprintf(&body, "[%s] %s from %s\n  %s\n  %s\n", entry.Timestamp, entry.Protocol, entry.IP, entrySummary(entry), entryAdminURL(entry))
+	}
+
+	subject := fmt.Sprintf("[SSRF Monitor] %d 件の新しいリクエスト (%s)", len(batch), domainHost())
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", a.sender())
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(a.to, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.BEncoding.Encode("UTF-8", subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\nContent-Type: text/plain; charset=UTF-8\r\nContent-Transfer-Encoding: 8bit\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(body.String(), "\n", "\r\n"))
+	return []byte(msg.String())
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// handleMemcached は memcached のテキストプロトコルを話し、受け取ったコマンドをそのまま記録する
+func handleMemcached(conn net.Conn) {
+	s := newSession(conn)
+	defer s.save("memcached")
+
+	for {
+		line, err := s.readLine()
+		if err != nil {
+			return
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		noreply := fields[len(fields)-1] == "noreply"
+
+		switch cmd := strings.ToLower(fields[0]); cmd {
+		case "get", "gets", "gat", "gats":
+			s.reply("END")
+		case "set", "add", "replace", "append", "prepend", "cas":
+			// <cmd> <key> <flags> <exptime> <bytes> [cas] [noreply] の後にデータブロックが続く
+			if len(fields) < 5 {
+				s.reply("ERROR")
+				continue
+			}
+			size, err := strconv.Atoi(fields[4])
+			if err != nil || size < 0 || size > maxCaptureSize {
+				s.reply("CLIENT_ERROR bad data chunk")
+				return
+			}
+			data := make([]byte, size+2)
+			n, err := io.ReadFull(s.reader, data)
+			s.record(&s.request, string(data[:n]))
+			if err != nil {
+				return
+			}
+			if !noreply {
+				s.reply("STORED")
+			}
+		case "delete", "incr", "decr":
+			if !noreply {
+				s.reply("NOT_FOUND")
+			}
+		case "touch":
+			if !noreply {
+				s.reply("TOUCHED")
+			}
+		case "flush_all", "verbosity":
+			if !noreply {
+				s.reply("OK")
+			}
+		case "version":
+			s.reply("VERSION 1.6.21")
+		case "stats":
+			for _, stat := range memcachedStats() {
+				s.reply("STAT %s", stat)
+			}
+			s.reply("END")
+		case "quit":
+			return
+		default:
+			s.reply("ERROR")
+		}
+	}
+}
+
+func memcachedStats() []string {
+	return []string{
+		"pid 1",
+		"uptime 1382941",
+		"time " + strconv.FormatInt(time.Now().Unix(), 10),
+		"version 1.6.21",
+		"pointer_size 64",
+		"curr_connections 4",
+		"total_connections 1204",
+		"cmd_get 58211",
+		"cmd_set 10934",
+		"get_hits 51002",
+		"get_misses 7209",
+		"curr_items 842",
+		"bytes 1048576",
+		"limit_maxbytes 67108864",
+		"threads 4",
+	}
+}
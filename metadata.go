@@ -0,0 +1,192 @@
+package main
+
+import (
+	"crypto/rand"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const metadataRoleName = "ssrf-canary-role"
+
+// 起動ごとに生成するカナリア用のクレデンシャル。外部で使われたら SSRF 経由の流出とわかる
+var (
+	canaryAccessKeyID = "ASIA" + randomString(16, "ABCDEFGHIJKLMNOPQRSTUVWXYZ234567")
+	canarySecretKey   = randomString(40, "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/")
+	canarySessionTok  = "IQoJb3JpZ2luX2VjE" + randomString(120, "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/")
+	canaryIMDSToken   = randomString(56, "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789_-") + "=="
+	canaryGCPToken    = "ya29.c." + randomString(150, "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789_-")
+	canaryAzureToken  = "eyJ0eXAiOiJKV1QiLCJhbGciOiJSUzI1NiJ9." + randomString(200, "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789_-")
+)
+
+func randomString(n int, alphabet string) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	for i := range b {
+		b[i] = alphabet[int(b[i])%len(alphabet)]
+	}
+	return string(b)
+}
+
+// emulateCloudMetadata は AWS / GCP / Azure のメタデータエンドポイントを模倣する
+func emulateCloudMetadata(r *http.Request) *mockResponse {
+	path := r.URL.Path
+	switch {
+	case strings.HasPrefix(path, "/latest/"):
+		return emulateAWSMetadata(r)
+	case strings.HasPrefix(path, "/computeMetadata/"):
+		return emulateGCPMetadata(r)
+	case strings.HasPrefix(path, "/metadata/"):
+		return emulateAzureMetadata(r)
+	}
+	return nil
+}
+
+func emulateAWSMetadata(r *http.Request) *mockResponse {
+	const tag = "aws-metadata"
+	if r.URL.Path == "/latest/api/token" {
+		if r.Method != http.MethodPut {
+			return textResponse(http.StatusMethodNotAllowed, "", tag)
+		}
+		res := textResponse(http.StatusOK, canaryIMDSToken, tag)
+		res.Header.Set("X-Aws-Ec2-Metadata-Token-Ttl-Seconds", "21600")
+		return res
+	}
+
+	expiration := time.Now().UTC().Add(6 * time.Hour).Format(time.RFC3339)
+	switch strings.TrimSuffix(r.URL.Path, "/") {
+	case "/latest", "/latest/meta-data":
+		return textResponse(http.StatusOK, strings.Join([]string{
+			"ami-id", "hostname", "iam/", "instance-id", "instance-type",
+			"local-hostname", "local-ipv4", "placement/", "public-ipv4", "security-groups",
+		}, "\n"), tag)
+	case "/latest/meta-data/ami-id":
+		return textResponse(http.StatusOK, "ami-0c55b159cbfafe1f0", tag)
+	case "/latest/meta-data/instance-id":
+		return textResponse(http.StatusOK, "i-0a1b2c3d4e5f67890", tag)
+	case "/latest/meta-data/instance-type":
+		return textResponse(http.StatusOK, "t3.medium", tag)
+	case "/latest/meta-data/hostname", "/latest/meta-data/local-hostname":
+		return textResponse(http.StatusOK, "ip-10-0-12-34.ec2.internal", tag)
+	case "/latest/meta-data/local-ipv4":
+		return textResponse(http.StatusOK, "10.0.12.34", tag)
+	case "/latest/meta-data/public-ipv4":
+		return textResponse(http.StatusOK, "203.0.113.45", tag)
+	case "/latest/meta-data/security-groups":
+		return textResponse(http.StatusOK, "default\nweb-prod", tag)
+	case "/latest/meta-data/placement":
+		return textResponse(http.StatusOK, "availability-zone\nregion", tag)
+	case "/latest/meta-data/placement/availability-zone":
+		return textResponse(http.StatusOK, "us-east-1a", tag)
+	case "/latest/meta-data/placement/region":
+		return textResponse(http.StatusOK, "us-east-1", tag)
+	case "/latest/meta-data/iam":
+		return textResponse(http.StatusOK, "info\nsecurity-credentials/", tag)
+	case "/latest/meta-data/iam/info":
+		return jsonResponse(http.StatusOK, fmt.Sprintf(`{
+  "Code" : "Success",
+  "LastUpdated" : "%s",
+  "InstanceProfileArn" : "arn:aws:iam::123456789012:instance-profile/%s",
+  "InstanceProfileId" : "AIPAJ2XU3ZQ4Y5EXAMPLE"
+}`, time.Now().UTC().Format(time.RFC3339), metadataRoleName), tag)
+	case "/latest/meta-data/iam/security-credentials":
+		return textResponse(http.StatusOK, metadataRoleName, tag)
+	case "/latest/meta-data/iam/security-credentials/" + metadataRoleName:
+		return jsonResponse(http.StatusOK, fmt.Sprintf(`{
+  "Code" : "Success",
+  "LastUpdated" : "%s",
+  "Type" : "AWS-HMAC",
+  "AccessKeyId" : "%s",
+  "SecretAccessKey" : "%s",
+  "Token" : "%s",
+  "Expiration" : "%s"
+}`, time.Now().UTC().Format(time.RFC3339), canaryAccessKeyID, canarySecretKey, canarySessionTok, expiration), tag, "credentials")
+	case "/latest/user-data":
+		return textResponse(http.StatusOK, "#!/bin/bash\nexport APP_ENV=production\n/opt/app/bin/start --config /etc/app/config.yml\n", tag)
+	case "/latest/dynamic/instance-identity/document":
+		return jsonResponse(http.StatusOK, `{
+  "accountId" : "123456789012",
+  "architecture" : "x86_64",
+  "availabilityZone" : "us-east-1a",
+  "imageId" : "ami-0c55b159cbfafe1f0",
+  "instanceId" : "i-0a1b2c3d4e5f67890",
+  "instanceType" : "t3.medium",
+  "privateIp" : "10.0.12.34",
+  "region" : "us-east-1",
+  "version" : "2017-09-30"
+}`, tag)
+	}
+	return textResponse(http.StatusNotFound, "Not Found", tag)
+}
+
+func emulateGCPMetadata(r *http.Request) *mockResponse {
+	const tag = "gcp-metadata"
+	if r.Header.Get("Metadata-Flavor") != "Google" {
+		return textResponse(http.StatusForbidden, "Missing Metadata-Flavor:Google header.", tag)
+	}
+
+	var res *mockResponse
+	switch strings.TrimSuffix(r.URL.Path, "/") {
+	case "/computeMetadata/v1":
+		res = textResponse(http.StatusOK, "instance/\nproject/", tag)
+	case "/computeMetadata/v1/project/project-id":
+		res = textResponse(http.StatusOK, "canary-project-123456", tag)
+	case "/computeMetadata/v1/project/numeric-project-id":
+		res = textResponse(http.StatusOK, "123456789012", tag)
+	case "/computeMetadata/v1/instance/hostname":
+		res = textResponse(http.StatusOK, "web-1.us-central1-a.c.canary-project-123456.internal", tag)
+	case "/computeMetadata/v1/instance/id":
+		res = textResponse(http.StatusOK, "4520031799277581759", tag)
+	case "/computeMetadata/v1/instance/zone":
+		res = textResponse(http.StatusOK, "projects/123456789012/zones/us-central1-a", tag)
+	case "/computeMetadata/v1/instance/service-accounts":
+		res = textResponse(http.StatusOK, "default/\n123456789012-compute@developer.gserviceaccount.com/", tag)
+	case "/computeMetadata/v1/instance/service-accounts/default/email":
+		res = textResponse(http.StatusOK, "123456789012-compute@developer.gserviceaccount.com", tag)
+	case "/computeMetadata/v1/instance/service-accounts/default/scopes":
+		res = textResponse(http.StatusOK, "https://www.googleapis.com/auth/cloud-platform", tag)
+	case "/computeMetadata/v1/instance/service-accounts/default/token":
+		res = jsonResponse(http.StatusOK, fmt.Sprintf(`{"access_token":"%s","expires_in":3599,"token_type":"Bearer"}`, canaryGCPToken), tag, "credentials")
+	default:
+		res = textResponse(http.StatusNotFound, "Not Found", tag)
+	}
+	res.Header.Set("Metadata-Flavor", "Google")
+	return res
+}
+
+func emulateAzureMetadata(r *http.Request) *mockResponse {
+	const tag = "azure-metadata"
+	if r.Header.Get("Metadata") != "true" {
+		return jsonResponse(http.StatusBadRequest, `{"error":"Bad request. Required metadata header not specified"}`, tag)
+	}
+
+	switch strings.TrimSuffix(r.URL.Path, "/") {
+	case "/metadata/instance":
+		return jsonResponse(http.StatusOK, `{
+  "compute": {
+    "azEnvironment": "AzurePublicCloud",
+    "location": "eastus",
+    "name": "web-prod-01",
+    "osType": "Linux",
+    "resourceGroupName": "rg-web-prod",
+    "subscriptionId": "8d9f5c2a-3b4e-4f6a-9c1d-2e7b8a0f1c3d",
+    "vmId": "02aab8a4-74ef-476e-8182-f6d2ba4166a6",
+    "vmSize": "Standard_D2s_v3"
+  },
+  "network": {
+    "interface": [{"ipv4": {"ipAddress": [{"privateIpAddress": "10.1.0.4", "publicIpAddress": "203.0.113.77"}]}}]
+  }
+}`, tag)
+	case "/metadata/identity/oauth2/token":
+		expiresOn := time.Now().Add(time.Hour).Unix()
+		return jsonResponse(http.StatusOK, fmt.Sprintf(`{
+  "access_token": "%s",
+  "expires_in": "3599",
+  "expires_on": "%d",
+  "resource": "%s",
+  "token_type": "Bearer"
+}`, canaryAzureToken, expiresOn, r.URL.Query().Get("resource")), tag, "credentials")
+	}
+	return jsonResponse(http.StatusNotFound, `{"error":"Not found"}`, tag)
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// metricsMaxPaths を超えた種類のパスは path="other" にまとめ、ラベルの数が増え続けないようにする
+const metricsMaxPaths = 500
+
+// metricsSizeBuckets はリクエストサイズのヒストグラムの境界（バイト）
+var metricsSizeBuckets = []float64{64, 256, 1024, 4096, 16384, 65536, 262144, 1048576}
+
+// metricsCollector は記録したエントリを数え、/metrics で Prometheus のテキスト形式で返す
+type metricsCollector struct {
+	mu       sync.Mutex
+	total    int64
+	byTarget map[[2]string]int64 // {listener, path}
+	paths    map[string]bool
+	buckets  []int64
+	sizeSum  float64
+}
+
+var metrics = &metricsCollector{
+	byTarget: map[[2]string]int64{},
+	paths:    map[string]bool{},
+	buckets:  make([]int64, len(metricsSizeBuckets)),
+}
+
+func (m *metricsCollector) add(entry LogEntry) {
+	path := entry.Path
+	size := float64(len(entry.RawRequest))
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if path != "" && !m.paths[path] {
+		if len(m.paths) < metricsMaxPaths {
+			m.paths[path] = true
+		} else {
+			path = "other"
+		}
+	}
+	m.total++
+	m.byTarget[[2]string{entry.Protocol, path}]++
+	for i, le := range metricsSizeBuckets {
+		if size <= le {
+			m.buckets[i]++
+		}
+	}
+	m.sizeSum += size
+}
+
+// handleMetrics は GET /metrics でカウンター・ゲージ・ヒストグラムを返す
+func handleMetrics(w http.ResponseWriter, r *http.Request) {
+	stored, err := store.Count()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	var b strings.Builder
+
+	m := metrics
+	m.mu.Lock()
+	fmt.Fprintf(&b, "# HELP ssrf_hits_total Entries recorded since start.\n# TYPE ssrf_hits_total counter\nssrf_hits_total %d\n", m.total)
+
+	targets := make([][2]string, 0, len(m.byTarget))
+	for t := range m.byTarget {
+		targets = append(targets, t)
+	}
+	sort.Slice(targets, func(i, j int) bool {
+		if targets[i][0] != targets[j][0] {
+			return targets[i][0] < targets[j][0]
+		}
+		return targets[i][1] < targets[j][1]
+	})
+	b.WriteString("# HELP ssrf_hits_by_target_total Entries recorded since start by listener and path.\n# TYPE ssrf_hits_by_target_total counter\n")
+	for _, t := range targets {
+		fmt.Fprintf(&b, "ssrf_hits_by_target_total{listener=%s,path=%s} %d\n", metricsLabel(t[0]), metricsLabel(t[1]), m.byTarget[t])
+	}
+
+	b.WriteString("# HELP ssrf_request_size_bytes Size of the recorded raw request.\n# TYPE ssrf_request_size_bytes histogram\n")
+	for i, le := range metricsSizeBuckets {
+		fmt.Fprintf(&b, "ssrf_request_size_bytes_bucket{le=\"%s\"} %d\n", strconv.FormatFloat(le, 'f', -1, 64), m.buckets[i])
+	}
+	fmt.Fprintf(&b, "ssrf_request_size_bytes_bucket{le=\"+Inf\"} %d\n", m.total)
+	fmt.Fprintf(&b, "ssrf_request_size_bytes_sum %g\nssrf_request_size_bytes_count %d\n", m.sizeSum, m.total)
+	m.mu.Unlock()
+
+	fmt.Fprintf(&b, "# HELP ssrf_stored_entries Entries currently in storage.\n# TYPE ssrf_stored_entries gauge\nssrf_stored_entries %d\n", stored)
+	fmt.Fprintf(&b, "# HELP ssrf_goroutines Number of goroutines.\n# TYPE ssrf_goroutines gauge\nssrf_goroutines %d\n", runtime.NumGoroutine())
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.Write([]byte(b.String()))
+}
+
+// metricsLabel はラベル値を引用符で囲み、\ と " と改行をエスケープする
+func metricsLabel(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s) + `"`
+}
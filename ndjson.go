@@ -0,0 +1,33 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// ndjsonFlushEvery は何件ごとにクライアントへ書き出すか
+const ndjsonFlushEvery = 100
+
+// handleExportNDJSON は保存先の全エントリを 1 行 1 件の JSON で返す。
+// Content-Length を付けずに途中で Flush するので chunked で流れる
+func handleExportNDJSON(w http.ResponseWriter, r *http.Request) {
+	logs, err := campaignLogs(r, 0)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Content-Disposition", `attachment; filename="ssrf_logs_`+time.Now().Format("20060102_150405")+`.ndjson"`)
+	flusher, _ := w.(http.Flusher)
+	enc := json.NewEncoder(w)
+	for i, entry := range logs {
+		if err := enc.Encode(entry); err != nil {
+			return
+		}
+		if flusher != nil && (i+1)%ndjsonFlushEvery == 0 {
+			flusher.Flush()
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"syscall"
+	"time"
+)
+
+// errInternalTarget は許可リストにない内部のアドレスへ接続しようとしたとき
+var errInternalTarget = errors.New("internal address not allowed")
+
+// internalIP はループバック・リンクローカル（169.254.169.254 のメタデータを含む）・プライベート・未指定のアドレスか
+func internalIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsPrivate() || ip.IsUnspecified()
+}
+
+func ipInNets(ip net.IP, nets []*net.IPNet) bool {
+	for _, n := range nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// guardedDialContext は内部のアドレスへの接続を *allow に含まれない限り拒否する DialContext を作る。
+// 名前解決の後の実際の接続先で確かめるので、DNS の書き換えやリダイレクトでも抜けられない
+func guardedDialContext(allow *[]*net.IPNet) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || (internalIP(ip) && !ipInNets(ip, *allow)) {
+				return fmt.Errorf("%w: %s", errInternalTarget, host)
+			}
+			return nil
+		},
+	}
+	return dialer.DialContext
+}
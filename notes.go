@@ -0,0 +1,43 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const noteMaxLen = 10000
+
+// handleEntryNote は PUT /api/logs/{id}/note で {"note": "..."} を受け取り、メモを置き換える。
+// 空文字ならメモを消す。更新後のエントリを返す
+func handleEntryNote(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+	var body struct {
+		Note string `json:"note"`
+	}
+	if err := json.NewDecoder(io.LimitReader(r.Body, noteMaxLen*4)).Decode(&body); err != nil {
+		http.Error(w, "invalid body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	note := strings.TrimSpace(body.Note)
+	if len(note) > noteMaxLen {
+		http.Error(w, "note too long", http.StatusRequestEntityTooLarge)
+		return
+	}
+	entry, ok, err := editEntry(id, func(entry *LogEntry) { entry.Note = note })
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	writeJSON(w, entry)
+}
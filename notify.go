@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const notifyQueueSize = 100
+
+// notifyFilter が設定されていれば、プロトコル名か生リクエストがマッチしたエントリだけ通知する
+var notifyFilter *regexp.Regexp
+
+var notifyClient = &http.Client{Timeout: 15 * time.Second}
+
+// notifier は新しいエントリを 1 件ずつ Slack などに通知する。送信はキュー経由で行い、記録を待たせない
+type notifier struct {
+	name  string
+	queue chan LogEntry
+	send  func(LogEntry) error
+}
+
+func newNotifier(name string, send func(LogEntry) error) *notifier {
+	n := &notifier{name: name, queue: make(chan LogEntry, notifyQueueSize), send: send}
+	go n.run()
+	return n
+}
+
+func (n *notifier) add(entry LogEntry) {
+	if !notifyMatch(entry) {
+		return
+	}
+	n.enqueue(entry)
+}
+
+// enqueue はフィルターを通さずにキューに積む。デッドレターからの再送でも使う
+func (n *notifier) enqueue(entry LogEntry) {
+	select {
+	case n.queue <- entry:
+	default:
+		deadLetters.park(n.name, entry, fmt.Errorf("queue full"), n.enqueue)
+	}
+}
+
+// notifyMatch は -notify-filter と -notify-rules を両方満たすかどうかを返す。アラートは常に通知し、
+// 期限切れの相関トークン宛てのものは通知しない
+func notifyMatch(entry LogEntry) bool {
+	if entry.Protocol == alertProtocol {
+		return true
+	}
+	if correlations.expired(entry.Token) {
+		return false
+	}
+	if notifyFilter != nil && !notifyFilter.MatchString(entry.Protocol) && !notifyFilter.MatchString(entry.RawRequest) {
+		return false
+	}
+	return notifyRulesMatch(entry)
+}
+
+// run は 1 件ずつ送る。失敗したら間隔を倍にしながら再送し、それでも届かなければデッドレターに残す
+func (n *notifier) run() {
+	for entry := range n.queue {
+		err := withRetry(func() (bool, error) { return true, n.send(entry) })
+		if err != nil {
+			deadLetters.park(n.name, entry, err, n.enqueue)
+		}
+	}
+}
+
+// entrySummary は通知の本文に使う 1 行の要約。HTTP ならメソッドと URL、それ以外は最初の行
+func entrySummary(entry LogEntry) string {
+	if req := entryRequest(entry); req != nil {
+		return req.Method + " " + harURL(entry, req)
+	}
+	line, _, _ := strings.Cut(strings.TrimSpace(entry.RawRequest), "\n")
+	return strings.TrimSpace(line)
+}
+
+// entryAdminURL は管理画面上のエントリを直接開く URL
+func entryAdminURL(entry LogEntry) string {
+	return fmt.Sprintf("http://%s/admin#log-%d", serverDomain, entry.ID)
+}
+
+// postJSON は v を JSON で POST し、2xx 以外をエラーにする
+func postJSON(url string, v any) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	resp, err := notifyClient.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// notifyRules は -notify-rules で読み込んだ通知の絞り込み。全ての通知先に共通で効く
+var notifyRules []notifyRule
+
+// notifyRule はルールファイルの 1 行。書式は "include|exclude <field> <value>" で、field と value は entryRule の通り。
+// exclude に 1 つでも当たれば通知しない。include が 1 つ以上あれば、どれかに当たったものだけ通知する
+type notifyRule struct {
+	include bool
+	entryRule
+}
+
+// entryRule は通知ルールとタグ付けルールに共通の条件。field は
+//
+//	path     HTTP のパス（glob、例 /token/*）
+//	host     HTTP の Host ヘッダー（glob、例 *.internal）
+//	ip       送信元（CIDR か IP、例 10.0.0.0/8）
+//	protocol プロトコル名（例 dns）
+//	tag      付与されたタグ
+//	raw      生リクエスト（正規表現）
+type entryRule struct {
+	field string
+	value string
+	cidr  *net.IPNet
+	re    *regexp.Regexp
+}
+
+func newEntryRule(field, value string) (entryRule, error) {
+	rule := entryRule{field: field, value: strings.TrimSpace(value)}
+	var err error
+	switch rule.field {
+	case "path", "host":
+		if _, err := path.Match(rule.value, ""); err != nil {
+			return rule, err
+		}
+	case "ip":
+		if !strings.Contains(rule.value, "/") {
+			if ip := net.ParseIP(rule.value); ip != nil && ip.To4() != nil {
+				rule.value += "/32"
+			} else {
+				rule.value += "/128"
+			}
+		}
+		if _, rule.cidr, err = net.ParseCIDR(rule.value); err != nil {
+			return rule, err
+		}
+	case "raw":
+		if rule.re, err = regexp.Compile(rule.value); err != nil {
+			return rule, err
+		}
+	case "protocol", "tag":
+	default:
+		return rule, fmt.Errorf("unknown field %q", rule.field)
+	}
+	return rule, nil
+}
+
+// readRuleLines は空行と # で始まる行を飛ばし、各行を 3 つに分けて fn に渡す
+func readRuleLines(name string, fn func(n int, parts []string) error) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if err := fn(n, strings.SplitN(line, " ", 3)); err != nil {
+			return fmt.Errorf("%s:%d: %v", name, n, err)
+		}
+	}
+	return scanner.Err()
+}
+
+func loadNotifyRules(name string) ([]notifyRule, error) {
+	var rules []notifyRule
+	err := readRuleLines(name, func(n int, parts []string) error {
+		if len(parts) != 3 || (parts[0] != "include" && parts[0] != "exclude") {
+			return fmt.Errorf("expected \"include|exclude <field> <value>\"")
+		}
+		rule, err := newEntryRule(parts[1], parts[2])
+		if err != nil {
+			return err
+		}
+		rules = append(rules, notifyRule{include: parts[0] == "include", entryRule: rule})
+		return nil
+	})
+	return rules, err
+}
+
+func (rule entryRule) match(entry LogEntry) bool {
+	switch rule.field {
+	case "path", "host":
+		entry = withRequestFields(entry)
+		if entry.Method == "" {
+			return false
+		}
+		value := entry.Path
+		if rule.field == "host" {
+			value = entry.Host
+		}
+		ok, _ := path.Match(rule.value, value)
+		return ok
+	case "ip":
+		ip := net.ParseIP(entry.IP)
+		return ip != nil && rule.cidr.Contains(ip)
+	case "protocol":
+		return entry.Protocol == rule.value
+	case "tag":
+		return slices.Contains(entry.Tags, rule.value)
+	case "raw":
+		return rule.re.MatchString(entry.RawRequest)
+	}
+	return false
+}
+
+// notifyRulesMatch はルールに照らして通知すべきかを返す
+func notifyRulesMatch(entry LogEntry) bool {
+	included, hasInclude := false, false
+	for _, rule := range notifyRules {
+		if !rule.include {
+			if rule.match(entry) {
+				return false
+			}
+			continue
+		}
+		hasInclude = true
+		if !included && rule.match(entry) {
+			included = true
+		}
+	}
+	return included || !hasInclude
+}
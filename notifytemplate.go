@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"text/template"
+)
+
+// notifyTemplateFuncs はテンプレートから使える関数。html / js / urlquery などの組み込みに加える
+var notifyTemplateFuncs = template.FuncMap{
+	"json": func(v any) string {
+		data, _ := json.Marshal(v)
+		return string(data)
+	},
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	"truncate": func(n int, s string) string {
+		if len(s) > n {
+			return s[:n]
+		}
+		return s
+	},
+	"join": strings.Join,
+}
+
+// notifyData はテンプレートに渡す値。LogEntry の項目に加えて HTTP の場合はメソッドなども参照できる
+//
+//	{{.Method}} {{.Host}}{{.Path}} {{.Header "User-Agent"}} {{.IP}} {{.Summary}} {{.AdminURL}}
+type notifyData struct {
+	LogEntry
+	Method   string
+	URL      string
+	Host     string
+	Path     string
+	Summary  string
+	AdminURL string
+	header   http.Header
+}
+
+func (d notifyData) Header(name string) string {
+	return d.header.Get(name)
+}
+
+// parseNotifyTemplate は値をそのままテンプレートとして読む。@ で始まればファイルから読む
+func parseNotifyTemplate(name, value string) (*template.Template, error) {
+	if value == "" {
+		return nil, nil
+	}
+	if path, ok := strings.CutPrefix(value, "@"); ok {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		value = string(data)
+	}
+	return template.New(name).Funcs(notifyTemplateFuncs).Option("missingkey=error").Parse(value)
+}
+
+// parseNotifyTemplates は通知先ごとのテンプレートをまとめて読む。値が空の通知先は nil になる
+func parseNotifyTemplates(values map[string]string) (map[string]*template.Template, error) {
+	templates := map[string]*template.Template{}
+	for name, value := range values {
+		t, err := parseNotifyTemplate(name, value)
+		if err != nil {
+			return nil, fmt.Errorf("%s template: %v", name, err)
+		}
+		templates[name] = t
+	}
+	return templates, nil
+}
+
+func renderNotify(t *template.Template, entry LogEntry) (string, error) {
+	data := notifyData{LogEntry: entry, Summary: entrySummary(entry), AdminURL: entryAdminURL(entry), header: http.Header{}}
+	if req := entryRequest(entry); req != nil {
+		data.Method, data.URL, data.Host, data.Path, data.header = req.Method, harURL(entry, req), req.Host, req.URL.Path, req.Header
+	}
+	var b strings.Builder
+	if err := t.Execute(&b, data); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
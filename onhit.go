@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"time"
+)
+
+const onHitQueueSize = 1000
+
+// hitCommand は新しいエントリごとに command を sh -c で起動し、エントリの JSON を標準入力に渡す。
+// 同時に動かすのは workers 個までで、timeout を過ぎたら止める
+type hitCommand struct {
+	command string
+	timeout time.Duration
+	queue   chan LogEntry
+}
+
+func newHitCommand(command string, workers int, timeout time.Duration) *hitCommand {
+	h := &hitCommand{command: command, timeout: timeout, queue: make(chan LogEntry, onHitQueueSize)}
+	for i := 0; i < max(workers, 1); i++ {
+		go h.worker()
+	}
+	return h
+}
+
+func (h *hitCommand) add(entry LogEntry) {
+	if correlations.expired(entry.Token) {
+		return
+	}
+	select {
+	case h.queue <- entry:
+	default:
+		fmt.Printf("On-hit Error: queue full, skipped entry %d\n", entry.ID)
+	}
+}
+
+func (h *hitCommand) worker() {
+	for entry := range h.queue {
+		if out, err := h.run(entry); err != nil {
+			fmt.Printf("On-hit Error: entry %d: %v\n%s", entry.ID, err, out)
+		}
+	}
+}
+
+func (h *hitCommand) run(entry LogEntry) ([]byte, error) {
+	input, err := json.Marshal(entry)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), h.timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", h.command)
+	cmd.Stdin = bytes.NewReader(append(input, '\n'))
+	// sh を止めても子プロセスが出力を握ったままだと戻らないので、少し待って打ち切る
+	cmd.WaitDelay = time.Second
+	// JSON を読まなくても簡単な処理ができるよう、主な項目は環境変数にも入れる
+	cmd.Env = append(os.Environ(),
+		"SSRF_ID="+strconv.FormatInt(entry.ID, 10),
+		"SSRF_PROTOCOL="+entry.Protocol,
+		"SSRF_IP="+entry.IP,
+		"SSRF_ADMIN_URL="+entryAdminURL(entry),
+	)
+	out, err := cmd.CombinedOutput()
+	if ctx.Err() == context.DeadlineExceeded {
+		err = fmt.Errorf("timed out after %s", h.timeout)
+	}
+	return out, err
+}
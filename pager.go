@@ -0,0 +1,98 @@
+package main
+
+import (
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+const (
+	adminDefaultPerPage = 50
+	adminMaxPerPage     = 500
+	adminMaxPage        = math.MaxInt32 // offset の計算があふれないように
+)
+
+// pager は管理画面のページ送りの状態
+type pager struct {
+	Page, PerPage, Total, Pages int
+	query                       url.Values // ページ番号以外のクエリ。リンクに引き継ぐ
+}
+
+// pageLink はページ番号のリンク 1 つ分。N が 0 なら省略記号
+type pageLink struct {
+	N       int
+	URL     string
+	Current bool
+}
+
+// parsePager は ?page=&per_page= を読む。per_page の既定値は 50 で、-limit が小さければそちらに合わせる
+func parsePager(r *http.Request) *pager {
+	q := r.URL.Query()
+	page, _ := strconv.Atoi(q.Get("page"))
+	perPage, _ := strconv.Atoi(q.Get("per_page"))
+	if perPage <= 0 {
+		perPage = min(adminDefaultPerPage, maxLogs)
+	}
+	q.Del("page")
+	for k, v := range q {
+		if len(v) == 0 || v[0] == "" {
+			q.Del(k)
+		}
+	}
+	return &pager{Page: min(max(page, 1), adminMaxPage), PerPage: min(perPage, adminMaxPerPage), query: q}
+}
+
+func (p *pager) offset() int {
+	return (p.Page - 1) * p.PerPage
+}
+
+// setTotal は件数からページ数を決め、範囲外のページを最後のページに寄せる
+func (p *pager) setTotal(total int) {
+	p.Total = total
+	p.Pages = max((total+p.PerPage-1)/p.PerPage, 1)
+	p.Page = min(p.Page, p.Pages)
+}
+
+func (p *pager) url(n int) string {
+	q := url.Values{}
+	for k, v := range p.query {
+		q[k] = v
+	}
+	if n > 1 {
+		q.Set("page", strconv.Itoa(n))
+	}
+	if len(q) == 0 {
+		return "/admin"
+	}
+	return "/admin?" + q.Encode()
+}
+
+func (p *pager) Prev() string {
+	if p.Page <= 1 {
+		return ""
+	}
+	return p.url(p.Page - 1)
+}
+
+func (p *pager) Next() string {
+	if p.Page >= p.Pages {
+		return ""
+	}
+	return p.url(p.Page + 1)
+}
+
+// Links は先頭と末尾、現在のページの前後 2 ページを返し、間は省略記号にする
+func (p *pager) Links() []pageLink {
+	var links []pageLink
+	for n := 1; n <= p.Pages; n++ {
+		if n != 1 && n != p.Pages && (n < p.Page-2 || n > p.Page+2) {
+			if links[len(links)-1].N != 0 {
+				links = append(links, pageLink{})
+			}
+			continue
+		}
+		links = append(links, pageLink{N: n, URL: p.url(n), Current: n == p.Page})
+	}
+	return links
+}
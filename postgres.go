@@ -0,0 +1,73 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+
+	_ "github.com/lib/pq"
+)
+
+// postgresMigrations は順番に適用するスキーマ変更。既存の要素は書き換えず、末尾に追加していく
+var postgresMigrations = []string{
+	`CREATE TABLE logs (
+		id        BIGINT PRIMARY KEY,
+		timestamp TEXT NOT NULL,
+		protocol  TEXT NOT NULL,
+		ip        TEXT NOT NULL,
+		entry     JSONB NOT NULL
+	)`,
+	`CREATE INDEX logs_protocol_idx ON logs (protocol)`,
+	// 既読・スター・アーカイブで一覧を絞り込めるよう、状態を列に出して既存の行は entry から埋める
+	`ALTER TABLE logs
+		ADD COLUMN archived BOOLEAN NOT NULL DEFAULT false,
+		ADD COLUMN starred  BOOLEAN NOT NULL DEFAULT false,
+		ADD COLUMN read     BOOLEAN NOT NULL DEFAULT false`,
+	`UPDATE logs SET
+		archived = COALESCE((entry->>'archived')::boolean, false),
+		starred  = COALESCE((entry->>'starred')::boolean, false),
+		read     = COALESCE((entry->>'read')::boolean, false)`,
+	`CREATE INDEX logs_state_idx ON logs (archived, read, starred, id)`,
+}
+
+// openPostgresStore は複数のインスタンスで共有できる PostgreSQL の保存先を開き、スキーマを最新にする
+func openPostgresStore(dsn string) (*sqlStore, error) {
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, err
+	}
+	if err := migratePostgres(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("migration: %w", err)
+	}
+	return &sqlStore{db: db, dollar: true}, nil
+}
+
+// migratePostgres は未適用のマイグレーションを 1 トランザクションで適用する。
+// 同時に起動したインスタンス同士はアドバイザリロックで直列化する
+func migratePostgres(db *sql.DB) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`SELECT pg_advisory_xact_lock(hashtext('go-ssrf-monitor'))`); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (version INTEGER PRIMARY KEY)`); err != nil {
+		return err
+	}
+	var current int
+	if err := tx.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&current); err != nil {
+		return err
+	}
+	for i := current; i < len(postgresMigrations); i++ {
+		if _, err := tx.Exec(postgresMigrations[i]); err != nil {
+			return fmt.Errorf("version %d: %w", i+1, err)
+		}
+		if _, err := tx.Exec(`INSERT INTO schema_migrations (version) VALUES ($1)`, i+1); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"strings"
+)
+
+var (
+	proxyEnabled bool
+	proxyBlocked []string     // ブロックするホスト（サフィックス一致、"*" は全て）
+	proxyAllow   []*net.IPNet // -proxy-allow。中継してよい内部のアドレス
+
+	// 環境変数のプロキシ設定は使わずに直接接続する。ループバック・プライベート・リンクローカルへは
+	// -proxy-allow にあるものしか中継しない（このサーバーの管理画面やメタデータに届かないように）
+	proxyTransport = func() *http.Transport {
+		t := http.DefaultTransport.(*http.Transport).Clone()
+		t.Proxy = nil
+		t.DialContext = guardedDialContext(&proxyAllow)
+		return t
+	}()
+)
+
+// teeReadCloser は読んだ内容を captureBuffer にも書きながら元の Body を閉じられるようにする
+type teeReadCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// captureBody は body を流しながら先頭の maxCaptureSize までを記録用に取っておく
+func captureBody(body io.ReadCloser) (io.ReadCloser, *captureBuffer) {
+	capture := &captureBuffer{}
+	return teeReadCloser{io.TeeReader(body, capture), body}, capture
+}
+
+// hop-by-hop ヘッダーは転送しない
+var hopHeaders = []string{
+	"Connection",
+	"Proxy-Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+// isProxyRequest は -proxy 有効時に絶対 URI（フォワードプロキシ形式）のリクエストかを判定する
+func isProxyRequest(r *http.Request) bool {
+	return proxyEnabled && r.URL.IsAbs()
+}
+
+func proxyHostBlocked(host string) bool {
+	host = strings.ToLower(host)
+	for _, b := range proxyBlocked {
+		b = strings.ToLower(b)
+		if b == "*" || host == b || strings.HasSuffix(host, "."+b) {
+			return true
+		}
+	}
+	return false
+}
+
+// handleProxy はリクエストを記録し、ブロックするか上流へ転送して、その応答も記録する
+func handleProxy(w http.ResponseWriter, r *http.Request) {
+	clientIP := requestClientIP(r)
+	requestDump, _ := httputil.DumpRequest(r, true)
+
+	blocked := func() {
+		body := "Blocked by proxy"
+		rawResponse := fmt.Sprintf("HTTP/1.1 403 Forbidden\n\n%s", body)
+		addLog(newLogEntry("proxy", clientIP, string(requestDump), rawResponse))
+		http.Error(w, body, http.StatusForbidden)
+	}
+	if proxyHostBlocked(r.URL.Hostname()) {
+		blocked()
+		return
+	}
+
+	outReq := r.Clone(r.Context())
+	outReq.RequestURI = ""
+	for _, h := range hopHeaders {
+		outReq.Header.Del(h)
+	}
+
+	resp, err := proxyTransport.RoundTrip(outReq)
+	if errors.Is(err, errInternalTarget) {
+		blocked()
+		return
+	}
+	if err != nil {
+		rawResponse := fmt.Sprintf("HTTP/1.1 502 Bad Gateway\n\n%v", err)
+		addLog(newLogEntry("proxy", clientIP, string(requestDump), rawResponse))
+		http.Error(w, "Bad Gateway", http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+
+	// ボディは全体を溜めずにそのまま流し、記録には先頭だけを使う
+	responseHeader, _ := httputil.DumpResponse(resp, false)
+	body, capture := captureBody(resp.Body)
+
+	for _, h := range hopHeaders {
+		resp.Header.Del(h)
+	}
+	for name, values := range resp.Header {
+		w.Header()[name] = values
+	}
+	w.WriteHeader(resp.StatusCode)
+	io.Copy(w, body)
+	addLog(newLogEntry("proxy", clientIP, string(requestDump), string(responseHeader)+string(capture.Bytes())))
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const proxyHeaderTimeout = 5 * time.Second
+
+// proxyProtocol が true の場合、各リスナーで PROXY protocol (v1/v2) ヘッダーを受け付ける
+var proxyProtocol bool
+
+// proxyProtocolFrom は -proxy-protocol-from。ヘッダーを信用するロードバランサーのアドレスで、
+// それ以外から直接来た接続はヘッダーを読まずに実際の接続元を使う（送信元を偽って -admin-allow などを抜けられないように）
+var proxyProtocolFrom []*net.IPNet
+
+var errMissingProxyHeader = errors.New("proxy protocol: missing header from trusted peer")
+
+var proxyV2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+
+// newListener は TCP で待ち受け、必要なら PROXY protocol 対応のリスナーで包む
+func newListener(addr string) (net.Listener, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil || !proxyProtocol {
+		return ln, err
+	}
+	return proxyProtoListener{ln}, nil
+}
+
+type proxyProtoListener struct {
+	net.Listener
+}
+
+func (l proxyProtoListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return &proxyProtoConn{Conn: conn, reader: bufio.NewReader(conn)}, nil
+}
+
+// proxyProtoConn は最初の Read か RemoteAddr の呼び出し時にヘッダーを読み、送信元アドレスを差し替える
+type proxyProtoConn struct {
+	net.Conn
+	reader *bufio.Reader
+
+	once         sync.Once
+	remote       net.Addr
+	err          error
+	mu           sync.Mutex
+	readDeadline time.Time
+}
+
+func (c *proxyProtoConn) init() {
+	c.once.Do(func() {
+		peer, ok := c.Conn.RemoteAddr().(*net.TCPAddr)
+		if !ok || !ipInNets(peer.IP, proxyProtocolFrom) {
+			return
+		}
+		c.Conn.SetReadDeadline(time.Now().Add(proxyHeaderTimeout))
+		c.remote, c.err = readProxyHeader(c.reader)
+		c.mu.Lock()
+		c.Conn.SetReadDeadline(c.readDeadline)
+		c.mu.Unlock()
+	})
+}
+
+func (c *proxyProtoConn) Read(b []byte) (int, error) {
+	c.init()
+	if c.err != nil {
+		return 0, c.err
+	}
+	return c.reader.Read(b)
+}
+
+func (c *proxyProtoConn) RemoteAddr() net.Addr {
+	c.init()
+	if c.remote != nil {
+		return c.remote
+	}
+	return c.Conn.RemoteAddr()
+}
+
+func (c *proxyProtoConn) SetDeadline(t time.Time) error {
+	c.mu.Lock()
+	c.readDeadline = t
+	c.mu.Unlock()
+	return c.Conn.SetDeadline(t)
+}
+
+func (c *proxyProtoConn) SetReadDeadline(t time.Time) error {
+	c.mu.Lock()
+	c.readDeadline = t
+	c.mu.Unlock()
+	return c.Conn.SetReadDeadline(t)
+}
+
+// readProxyHeader は v1/v2 ヘッダーを読んで送信元を返す。UNKNOWN や LOCAL なら nil を返す。
+// 信用するロードバランサーからの接続なので、ヘッダーがなければエラーにする
+func readProxyHeader(r *bufio.Reader) (net.Addr, error) {
+	first, err := r.Peek(1)
+	if err != nil {
+		return nil, err
+	}
+	switch first[0] {
+	case 'P':
+		if sig, err := r.Peek(6); err == nil && string(sig) == "PROXY " {
+			return readProxyV1(r)
+		}
+	case '\r':
+		if sig, err := r.Peek(len(proxyV2Signature)); err == nil && bytes.Equal(sig, proxyV2Signature) {
+			return readProxyV2(r)
+		}
+	}
+	return nil, errMissingProxyHeader
+}
+
+// readProxyV1 は "PROXY TCP4 <src> <dst> <sport> <dport>\r\n" を解釈する
+func readProxyV1(r *bufio.Reader) (net.Addr, error) {
+	var line []byte
+	for len(line) < 108 {
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		line = append(line, b)
+		if b == '\n' {
+			break
+		}
+	}
+	fields := strings.Fields(string(line))
+	if len(fields) < 2 {
+		return nil, errors.New("proxy protocol: malformed v1 header")
+	}
+	if fields[1] == "UNKNOWN" {
+		return nil, nil
+	}
+	if len(fields) != 6 {
+		return nil, errors.New("proxy protocol: malformed v1 header")
+	}
+	ip := net.ParseIP(fields[2])
+	port, err := strconv.Atoi(fields[4])
+	if ip == nil || err != nil {
+		return nil, errors.New("proxy protocol: invalid v1 address")
+	}
+	return &net.TCPAddr{IP: ip, Port: port}, nil
+}
+
+// readProxyV2 はバイナリ形式のヘッダーを解釈する
+func readProxyV2(r *bufio.Reader) (net.Addr, error) {
+	header := make([]byte, 16)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+	if header[12]>>4 != 2 {
+		return nil, errors.New("proxy protocol: unsupported v2 version")
+	}
+	payload := make([]byte, binary.BigEndian.Uint16(header[14:16]))
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, err
+	}
+	if header[12]&0x0f == 0 { // LOCAL（ヘルスチェックなど）は実際の接続元を使う
+		return nil, nil
+	}
+
+	switch header[13] >> 4 {
+	case 1: // AF_INET
+		if len(payload) < 12 {
+			return nil, errors.New("proxy protocol: short v2 address")
+		}
+		return &net.TCPAddr{IP: net.IP(payload[0:4]), Port: int(binary.BigEndian.Uint16(payload[8:10]))}, nil
+	case 2: // AF_INET6
+		if len(payload) < 36 {
+			return nil, errors.New("proxy protocol: short v2 address")
+		}
+		return &net.TCPAddr{IP: net.IP(payload[0:16]), Port: int(binary.BigEndian.Uint16(payload[32:34]))}, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// rateLimiter は送信元 IP ごとのトークンバケット。rate 件/秒で補充し、burst 件まで貯まる
+type rateLimiter struct {
+	mu      sync.Mutex
+	rate    float64
+	burst   float64
+	buckets map[string]*bucket
+	swept   time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// httpLimiter は -rate-limit。nil なら制限しない
+var httpLimiter *rateLimiter
+
+func newRateLimiter(rate float64, burst int) *rateLimiter {
+	return &rateLimiter{rate: rate, burst: float64(max(burst, 1)), buckets: map[string]*bucket{}}
+}
+
+// allow は key から 1 件受け付けてよいかを返す
+func (l *rateLimiter) allow(key string) bool {
+	now := time.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.sweep(now)
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// sweep は満タンに戻ったバケットを捨て、送信元が多くてもメモリが増え続けないようにする
+func (l *rateLimiter) sweep(now time.Time) {
+	if now.Sub(l.swept) < time.Minute {
+		return
+	}
+	l.swept = now
+	full := time.Duration(l.burst / l.rate * float64(time.Second))
+	for key, b := range l.buckets {
+		if now.Sub(b.last) > full {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// rateLimited は記録用のリクエストが制限を超えていれば 429 を返して true。
+// X-Forwarded-For は偽装できるので接続元のアドレス（PROXY protocol 有効時はその送信元）で数える
+func rateLimited(w http.ResponseWriter, r *http.Request) bool {
+	if httpLimiter == nil {
+		return false
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	if httpLimiter.allow(host) {
+		return false
+	}
+	if tarpitInterval > 0 {
+		// 引き止めている接続が上限に達していれば普通の 429 にする
+		if acquireHold() {
+			defer releaseHold()
+			res := &mockResponse{
+				Status:  http.StatusTooManyRequests,
+				Header:  http.Header{"Retry-After": {"1"}},
+				Body:    "429 Too Many Requests\n",
+				Drip:    tarpitInterval,
+				DripFor: tarpitDuration,
+			}
+			res.drip(w, r)
+			return true
+		}
+	}
+	w.Header().Set("Retry-After", "1")
+	http.Error(w, "429 Too Many Requests", http.StatusTooManyRequests)
+	return true
+}
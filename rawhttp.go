@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+const rawHTTPTrailingWait = 300 * time.Millisecond
+
+var contentLengthPattern = regexp.MustCompile(`(?im)^content-length:[ \t]*(\d+)`)
+
+// handleRawHTTP は net/http を通さずにソケットから読んだバイト列をそのまま記録し、固定の 200 を返す
+func handleRawHTTP(conn net.Conn) {
+	data := readRawHTTPRequest(conn)
+
+	responseBody := "Active"
+	rawResponse := fmt.Sprintf("HTTP/1.1 200 OK\r\nContent-Type: text/plain; charset=utf-8\r\nContent-Length: %d\r\nConnection: close\r\n\r\n%s",
+		len(responseBody), responseBody)
+	conn.Write([]byte(rawResponse))
+
+	addLog(newLogEntry("raw-http", remoteIP(conn), dumpBytes(data), rawResponse))
+}
+
+// readRawHTTPRequest はリクエストが揃うまで読み、その後に続く（スマグリングされた）バイトも少しだけ待って拾う
+func readRawHTTPRequest(conn net.Conn) []byte {
+	var data []byte
+	buf := make([]byte, 4096)
+	deadline := tcpIdleTimeout
+	for len(data) < maxCaptureSize {
+		conn.SetReadDeadline(time.Now().Add(deadline))
+		n, err := conn.Read(buf)
+		data = append(data, buf[:n]...)
+		if err != nil {
+			break
+		}
+		if rawHTTPComplete(data) {
+			deadline = rawHTTPTrailingWait
+		}
+	}
+	return data
+}
+
+// rawHTTPComplete はヘッダーと Content-Length / chunked の本文が揃ったかを大まかに判定する
+func rawHTTPComplete(data []byte) bool {
+	end, sep := bytes.Index(data, []byte("\r\n\r\n")), 4
+	if end < 0 {
+		end, sep = bytes.Index(data, []byte("\n\n")), 2
+	}
+	if end < 0 {
+		return false
+	}
+	headers, body := data[:end], data[end+sep:]
+
+	if bytes.Contains(bytes.ToLower(headers), []byte("transfer-encoding: chunked")) {
+		return bytes.Contains(body, []byte("0\r\n\r\n"))
+	}
+	if m := contentLengthPattern.FindSubmatch(headers); m != nil {
+		n, _ := strconv.Atoi(string(m[1]))
+		return len(body) >= n
+	}
+	return true
+}
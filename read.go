@@ -0,0 +1,65 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// handleEntryRead は POST /api/logs/{id}/read で既読にし、DELETE で未読に戻す。更新後のエントリを返す
+func handleEntryRead(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+	entry, ok, err := editEntry(id, func(entry *LogEntry) { entry.Read = r.Method == http.MethodPost })
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	writeJSON(w, entry)
+}
+
+// handleMarkRead は POST /api/logs/read で /api/logs と同じ条件に合う未読のエントリを全て既読にし、件数を返す。
+// 条件がなければ全件
+func handleMarkRead(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseLogFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	filter.unread = true
+	logs, _, err := queryLogs(filter, 0, -1)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	n := 0
+	for _, entry := range logs {
+		if _, ok, err := editEntry(entry.ID, func(entry *LogEntry) { entry.Read = true }); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		} else if ok {
+			n++
+		}
+	}
+	writeJSON(w, map[string]int{"marked": n})
+}
+
+// handleUnread は GET /api/unread で未読の件数を返す。アーカイブしたものは数えない
+func handleUnread(w http.ResponseWriter, r *http.Request) {
+	n, err := unreadCount()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, map[string]int{"unread": n})
+}
+
+func unreadCount() (int, error) {
+	return store.CountState(stateFilter{archived: "0", read: "0"})
+}
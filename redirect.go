@@ -0,0 +1,95 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// maxChainHops は /chain/{n} の n の上限
+const maxChainHops = 100
+
+// redirectResponse は /redirect?to=<url>&code=302 を記録してから to へリダイレクトする。
+// ホスト名の検査の後でリダイレクトを追って内部のホストへ行くかを試す用
+func redirectResponse(r *http.Request) *mockResponse {
+	if r.URL.Path != "/redirect" {
+		return nil
+	}
+	q := r.URL.Query()
+	code, ok := redirectCode(q)
+	if !ok {
+		return &mockResponse{Status: http.StatusBadRequest, Body: redirectCodeError, Tags: []string{"redirect"}}
+	}
+	to := q.Get("to")
+	if !redirectTarget(to) {
+		return &mockResponse{Status: http.StatusBadRequest, Body: "to must be an absolute url", Tags: []string{"redirect"}}
+	}
+	return &mockResponse{
+		Status: code,
+		Header: http.Header{"Location": {to}},
+		Tags:   []string{"redirect"},
+	}
+}
+
+// chainResponse は /chain/{n}?to=<url> で /chain/{n-1} へ、/chain/1 で to（省略時 /）へリダイレクトし、
+// n 回のリダイレクトの後に to に着くようにする。/loop は hop を数えながら自分自身へ際限なくリダイレクトする。
+// どちらも相対 URL で返すので、サブドメインの相関 ID を保ったまま各 hop が記録される
+func chainResponse(r *http.Request) *mockResponse {
+	q := r.URL.Query()
+	code, codeOK := redirectCode(q)
+	if r.URL.Path == "/loop" {
+		if !codeOK {
+			return &mockResponse{Status: http.StatusBadRequest, Body: redirectCodeError, Tags: []string{"loop"}}
+		}
+		hop, _ := strconv.Atoi(q.Get("hop"))
+		q.Set("hop", strconv.Itoa(hop+1))
+		return &mockResponse{Status: code, Header: http.Header{"Location": {"/loop?" + q.Encode()}}, Tags: []string{"loop"}}
+	}
+	rest, ok := strings.CutPrefix(r.URL.Path, "/chain/")
+	if !ok {
+		return nil
+	}
+	n, err := strconv.Atoi(rest)
+	if err != nil || n < 1 || n > maxChainHops {
+		return &mockResponse{Status: http.StatusBadRequest, Body: "n must be between 1 and " + strconv.Itoa(maxChainHops), Tags: []string{"chain"}}
+	}
+	if !codeOK {
+		return &mockResponse{Status: http.StatusBadRequest, Body: redirectCodeError, Tags: []string{"chain"}}
+	}
+	// 最後の 1 回だけでなく最初の 1 回目から to を検査し、途中まで辿らせてから 400 にしない
+	to := q.Get("to")
+	if to != "" && !redirectTarget(to) {
+		return &mockResponse{Status: http.StatusBadRequest, Body: "to must be an absolute url", Tags: []string{"chain"}}
+	}
+	location := "/chain/" + strconv.Itoa(n-1) + "?" + r.URL.RawQuery
+	if n == 1 {
+		location = to
+		if location == "" {
+			location = "/"
+		}
+	}
+	return &mockResponse{Status: code, Header: http.Header{"Location": {location}}, Tags: []string{"chain"}}
+}
+
+const redirectCodeError = "code must be 301, 302, 303, 307 or 308"
+
+// redirectCode は ?code= のリダイレクトのステータス。省略時は 302
+func redirectCode(q url.Values) (int, bool) {
+	s := q.Get("code")
+	if s == "" {
+		return http.StatusFound, true
+	}
+	code, _ := strconv.Atoi(s)
+	return code, redirectStatus(code)
+}
+
+// redirectTarget はリダイレクト先に使える絶対 URL か。gopher://、file://、dict:// などへの切り替えを試せるよう
+// スキームは問わず、http(s) だけホストを必須にする
+func redirectTarget(s string) bool {
+	u, err := url.Parse(s)
+	if err != nil || u.Scheme == "" {
+		return false
+	}
+	return (u.Scheme != "http" && u.Scheme != "https") || u.Host != ""
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// handleRedis は PING/INFO/AUTH などに応答する Redis として振る舞い、受け取ったコマンドを全て記録する
+func handleRedis(conn net.Conn) {
+	s := newSession(conn)
+	defer s.save("redis")
+
+	for {
+		args, err := readRESPCommand(s.reader)
+		if err != nil {
+			return
+		}
+		if len(args) == 0 {
+			continue
+		}
+		quoted := make([]string, len(args))
+		for i, a := range args {
+			quoted[i] = quoteArg(a)
+		}
+		s.record(&s.request, strings.Join(quoted, " ")+"\n")
+
+		switch strings.ToUpper(args[0]) {
+		case "PING":
+			if len(args) > 1 {
+				s.replyBulk(args[1])
+			} else {
+				s.reply("+PONG")
+			}
+		case "ECHO":
+			if len(args) > 1 {
+				s.replyBulk(args[1])
+			}
+		case "INFO":
+			s.replyBulk(redisInfo())
+		case "AUTH", "SELECT", "SET", "CONFIG", "SAVE", "BGSAVE", "FLUSHALL", "FLUSHDB",
+			"SLAVEOF", "REPLICAOF", "MODULE", "EXPIRE", "DEL", "HSET", "LPUSH", "RPUSH", "CLIENT":
+			s.reply("+OK")
+		case "GET", "HGET":
+			s.reply("$-1")
+		case "KEYS", "SCAN":
+			s.reply("*0")
+		case "DBSIZE":
+			s.reply(":0")
+		case "QUIT":
+			s.reply("+OK")
+			return
+		default:
+			s.reply("-ERR unknown command '%s'", args[0])
+		}
+	}
+}
+
+// quoteArg は空白や制御文字を含む引数だけを引用符で囲む
+func quoteArg(a string) string {
+	if q := strconv.Quote(a); a == "" || q[1:len(q)-1] != a || strings.ContainsAny(a, " \t") {
+		return q
+	}
+	return a
+}
+
+func (s *session) replyBulk(v string) {
+	s.reply("$%d\r\n%s", len(v), v)
+}
+
+func redisInfo() string {
+	return strings.Join([]string{
+		"# Server",
+		"redis_version:6.2.14",
+		"redis_mode:standalone",
+		"os:Linux 5.15.0-91-generic x86_64",
+		"arch_bits:64",
+		"tcp_port:6379",
+		"uptime_in_seconds:1382941",
+		"# Clients",
+		"connected_clients:3",
+		"# Memory",
+		"used_memory:1048576",
+		"used_memory_human:1.00M",
+		"# Persistence",
+		"rdb_last_save_time:1700000000",
+		"# Replication",
+		"role:master",
+		"connected_slaves:0",
+		"# Keyspace",
+		"db0:keys=12,expires=0,avg_ttl=0",
+		"",
+	}, "\r\n")
+}
+
+// readRESPCommand は RESP 配列形式、またはインライン形式（gopher/dict 経由でよく使われる）のコマンドを 1 つ読む
+func readRESPCommand(r *bufio.Reader) ([]string, error) {
+	line, err := readCRLFLine(r)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(line, "*") {
+		return strings.Fields(line), nil
+	}
+
+	n, err := strconv.Atoi(line[1:])
+	if err != nil || n < 0 || n > 1024 {
+		return nil, errors.New("invalid multibulk length")
+	}
+	args := make([]string, 0, n)
+	// 引数ごとではなくコマンド全体で maxCaptureSize までにする（1 件ずつの上限だと 1024 倍まで確保できてしまう）
+	budget := maxCaptureSize
+	for range n {
+		header, err := readCRLFLine(r)
+		if err != nil {
+			return nil, err
+		}
+		if !strings.HasPrefix(header, "$") {
+			return nil, fmt.Errorf("expected '$', got %q", header)
+		}
+		size, err := strconv.Atoi(header[1:])
+		if err != nil || size < 0 || size > budget {
+			return nil, errors.New("invalid bulk length")
+		}
+		budget -= size
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		args = append(args, string(buf[:size]))
+	}
+	return args, nil
+}
+
+func readCRLFLine(r *bufio.Reader) (string, error) {
+	var line []byte
+	for {
+		chunk, err := r.ReadSlice('\n')
+		line = append(line, chunk...)
+		if len(line) > maxCaptureSize {
+			return "", errors.New("line too long")
+		}
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		if err != nil && len(line) == 0 {
+			return "", err
+		}
+		return strings.TrimRight(string(line), "\r\n"), nil
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"time"
+)
+
+// replayAllow は -replay-allow。再送先にしてよい内部のアドレス
+var replayAllow []*net.IPNet
+
+// replayClient はリダイレクトを追わず、検証環境の自己署名証明書も受け入れる。
+// このサーバー自身のネットワークやクラウドのメタデータへの中継にならないよう、内部のアドレスへは -replay-allow にあるものしか送らない。
+// 環境変数のプロキシを使うとその確認が効かないので直接接続する
+var replayClient = &http.Client{
+	Timeout:       30 * time.Second,
+	Transport:     &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}, DialContext: guardedDialContext(&replayAllow)},
+	CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+}
+
+// replayOptions は POST /api/logs/{id}/replay の本文
+type replayOptions struct {
+	Target        string            `json:"target"`         // 送り先（例 http://localhost:8080）。スキームとホストを差し替える
+	KeepHost      bool              `json:"keep_host"`      // 元の Host ヘッダーのまま送る
+	SetHeaders    map[string]string `json:"set_headers"`    // 追加・上書きするヘッダー
+	RemoveHeaders []string          `json:"remove_headers"` // 取り除くヘッダー
+}
+
+// handleReplay は保存したリクエストを target に送り直し、送ったリクエストと受け取ったレスポンスを
+// 元のエントリに紐づく replay エントリとして記録して返す
+func handleReplay(w http.ResponseWriter, r *http.Request) {
+	// 認証がなければ誰でも任意の宛先へリクエストを送らせられるので受け付けない
+	if !authEnabled() {
+		http.Error(w, "replay requires authentication (-admin-user or -user-file)", http.StatusForbidden)
+		return
+	}
+	entry, ok := entryFromPath(w, r)
+	if !ok {
+		return
+	}
+	orig := entryRequest(entry)
+	if orig == nil {
+		http.Error(w, "not an HTTP entry", http.StatusUnprocessableEntity)
+		return
+	}
+	var opts replayOptions
+	if err := json.NewDecoder(io.LimitReader(r.Body, 1<<16)).Decode(&opts); err != nil {
+		http.Error(w, "invalid body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	target, err := retarget(harURL(entry, orig), opts.Target)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	body, _ := io.ReadAll(orig.Body)
+	req, err := http.NewRequestWithContext(r.Context(), orig.Method, target, bytes.NewReader(body))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	for name, values := range orig.Header {
+		if !curlSkipHeaders[name] {
+			req.Header[name] = values
+		}
+	}
+	if opts.KeepHost {
+		req.Host = orig.Host
+	}
+	for _, name := range opts.RemoveHeaders {
+		req.Header.Del(name)
+	}
+	for name, value := range opts.SetHeaders {
+		if http.CanonicalHeaderKey(name) == "Host" {
+			req.Host = value
+			continue
+		}
+		req.Header.Set(name, value)
+	}
+	requestDump, _ := httputil.DumpRequestOut(req, true)
+	req.Body = io.NopCloser(bytes.NewReader(body))
+
+	var responseDump string
+	res, err := replayClient.Do(req)
+	if err != nil {
+		responseDump = "(error) " + err.Error()
+	} else {
+		resBody, _ := io.ReadAll(io.LimitReader(res.Body, maxCaptureSize))
+		res.Body.Close()
+		res.Body = io.NopCloser(bytes.NewReader(resBody))
+		dump, _ := httputil.DumpResponse(res, true)
+		responseDump = string(dump)
+	}
+
+	replay := newLogEntry("replay", requestClientIP(r), string(requestDump), responseDump)
+	replay.ParentID = entry.ID
+	replay.Read = true
+	writeJSON(w, addLog(replay))
+}
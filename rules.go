@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// responseRule は -rules のルール 1 件。match に当てはまったリクエストに response を返す。
+// path・host・ヘッダーの値は glob（例 /latest/*、*.internal）で、省略した条件は何にでも当てはまる。
+// ヘッダーの値を空にするとそのヘッダーがあるだけでよい
+type responseRule struct {
+	Match struct {
+		Method  string            `yaml:"method"`
+		Path    string            `yaml:"path"`
+		Host    string            `yaml:"host"`
+		Headers map[string]string `yaml:"headers"`
+	} `yaml:"match"`
+	Response struct {
+		Status  int               `yaml:"status"`
+		Headers map[string]string `yaml:"headers"`
+		Body    string            `yaml:"body"`
+		Tags    []string          `yaml:"tags"`
+	} `yaml:"response"`
+}
+
+// loadResponseRules は YAML か JSON のルールの配列を読む。先に書いたルールが優先される
+func loadResponseRules(name string) ([]responseRule, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	var rules []responseRule
+	if err := yaml.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	for i := range rules {
+		if err := rules[i].validate(); err != nil {
+			return nil, fmt.Errorf("%s: rule %d: %v", name, i+1, err)
+		}
+	}
+	return rules, nil
+}
+
+// validate は glob とステータスを検査し、省略したステータスを 200 にする
+func (rule *responseRule) validate() error {
+	rule.Match.Method = strings.ToUpper(rule.Match.Method)
+	patterns := []string{rule.Match.Path, strings.ToLower(rule.Match.Host)}
+	for _, v := range rule.Match.Headers {
+		patterns = append(patterns, v)
+	}
+	for _, p := range patterns {
+		if _, err := path.Match(p, ""); err != nil {
+			return fmt.Errorf("invalid pattern %q", p)
+		}
+	}
+	if rule.Response.Status == 0 {
+		rule.Response.Status = http.StatusOK
+	}
+	if rule.Response.Status < 100 || rule.Response.Status > 599 {
+		return fmt.Errorf("invalid status %d", rule.Response.Status)
+	}
+	for i, tag := range rule.Response.Tags {
+		var err error
+		if rule.Response.Tags[i], err = normalizeTag(tag); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// globMatch は pattern が空なら常に当てはまる
+func globMatch(pattern, s string) bool {
+	ok, _ := path.Match(pattern, s)
+	return pattern == "" || ok
+}
+
+func (rule *responseRule) match(r *http.Request) bool {
+	if rule.Match.Method != "" && rule.Match.Method != r.Method {
+		return false
+	}
+	if !globMatch(rule.Match.Path, r.URL.Path) || !globMatch(strings.ToLower(rule.Match.Host), strings.ToLower(hostWithoutPort(r.Host))) {
+		return false
+	}
+	for name, pattern := range rule.Match.Headers {
+		if !slices.ContainsFunc(r.Header.Values(name), func(v string) bool { return globMatch(pattern, v) }) {
+			return false
+		}
+	}
+	return true
+}
+
+// emulateRules は -rules のルールを emulators の先頭に入れるための emulator を作る
+func emulateRules(rules []responseRule) emulator {
+	return func(r *http.Request) *mockResponse {
+		for _, rule := range rules {
+			if !rule.match(r) {
+				continue
+			}
+			header := http.Header{}
+			for name, v := range rule.Response.Headers {
+				header.Set(name, v)
+			}
+			return &mockResponse{
+				Status: rule.Response.Status,
+				Header: header,
+				Body:   rule.Response.Body,
+				Tags:   append([]string{"rule"}, rule.Response.Tags...),
+			}
+		}
+		return nil
+	}
+}
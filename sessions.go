@@ -0,0 +1,193 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"html/template"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const sessionCookie = "ssrf_session"
+
+// loginSession はログインフォームから作るサーバー側のセッション。ロールは毎回ユーザーから引き直す
+type loginSession struct {
+	user     string
+	lastSeen time.Time
+}
+
+// sessionStore はメモリ上のセッション。再起動すると全員ログインし直しになる
+type sessionStore struct {
+	mu       sync.Mutex
+	idle     time.Duration
+	sessions map[string]*loginSession
+	swept    time.Time
+}
+
+var sessions = &sessionStore{idle: 30 * time.Minute, sessions: map[string]*loginSession{}}
+
+func (s *sessionStore) create(user string) string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	id := hex.EncodeToString(b)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sessions[id] = &loginSession{user: user, lastSeen: time.Now()}
+	return id
+}
+
+// get は id のセッションのユーザー名を返し、最終アクセス時刻を進める。idle を過ぎていれば消す
+func (s *sessionStore) get(id string) (string, bool) {
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if now.Sub(s.swept) > time.Minute {
+		s.swept = now
+		for k, v := range s.sessions {
+			if now.Sub(v.lastSeen) > s.idle {
+				delete(s.sessions, k)
+			}
+		}
+	}
+	sess, ok := s.sessions[id]
+	if !ok || now.Sub(sess.lastSeen) > s.idle {
+		delete(s.sessions, id)
+		return "", false
+	}
+	sess.lastSeen = now
+	return sess.user, true
+}
+
+func (s *sessionStore) remove(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions, id)
+}
+
+// removeUser はユーザーの削除やパスワード変更のときにそのユーザーのセッションを全て消す
+func (s *sessionStore) removeUser(user string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for k, v := range s.sessions {
+		if v.user == user {
+			delete(s.sessions, k)
+		}
+	}
+}
+
+// sessionID はリクエストのセッション Cookie の値
+func sessionID(r *http.Request) string {
+	c, err := r.Cookie(sessionCookie)
+	if err != nil {
+		return ""
+	}
+	return c.Value
+}
+
+// sessionPrincipal はセッション Cookie からログイン中のユーザーを引く。ユーザーが消えていればセッションも無効
+func sessionPrincipal(r *http.Request) (principal, bool) {
+	id := sessionID(r)
+	if id == "" {
+		return principal{}, false
+	}
+	user, ok := sessions.get(id)
+	if !ok {
+		return principal{}, false
+	}
+	ro := roleAdmin
+	if user != adminAuth.user {
+		if ro = adminUsers.role(user); ro == roleNone {
+			sessions.remove(id)
+			return principal{}, false
+		}
+	}
+	return principal{Name: user, Role: ro, Session: true}, true
+}
+
+// loginPath はログインフォームとログアウトのパス。認証なしで通す
+func loginPath(path string) bool {
+	return path == "/login" || path == "/logout"
+}
+
+// wantsLoginPage はブラウザで管理画面を開こうとしているかを返す。その場合は 401 ではなくログインフォームへ送る
+func wantsLoginPage(r *http.Request) bool {
+	return r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/admin") &&
+		strings.Contains(r.Header.Get("Accept"), "text/html")
+}
+
+// localRedirect は next がこのサーバーのパスならそれを、そうでなければ /admin を返す
+func localRedirect(next string) string {
+	if !strings.HasPrefix(next, "/") || strings.HasPrefix(next, "//") || strings.HasPrefix(next, "/\\") {
+		return "/admin"
+	}
+	return next
+}
+
+var loginTmpl = template.Must(template.New("login").Parse(`<!DOCTYPE html>
+<html lang="ja">
+<head>
+    <title>ログイン - SSRF Monitor</title>
+    <meta charset="utf-8">
+    <style>
+        body { font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", Roboto, sans-serif; background: #f0f2f5; color: #1c1e21; }
+        form { background: #fff; max-width: 320px; margin: 120px auto; padding: 30px; border-radius: 12px; box-shadow: 0 4px 12px rgba(0,0,0,0.05); }
+        input { display: block; width: 100%; box-sizing: border-box; margin-bottom: 12px; padding: 10px; border: 1px solid #ddd; border-radius: 6px; }
+        button { width: 100%; padding: 10px; border: none; border-radius: 6px; background: #1877f2; color: white; font-weight: 600; cursor: pointer; }
+        .error { color: #dc3545; font-size: 14px; margin-bottom: 12px; }
+    </style>
+</head>
+<body>
+    <form method="post" action="/login">
+        <h1 style="margin-top:0; font-size: 22px;">SSRF Monitor</h1>
+        {{if .Error}}<div class="error">{{.Error}}</div>{{end}}
+        <input name="user" placeholder="ユーザー名" autocomplete="username" autofocus required>
+        <input name="password" type="password" placeholder="パスワード" autocomplete="current-password" required>
+        <input type="hidden" name="next" value="{{.Next}}">
+        <input type="hidden" name="csrf_token" value="{{.CSRF}}">
+        <button type="submit">ログイン</button>
+    </form>
+</body>
+</html>
+`))
+
+// handleLogin は GET /login でフォームを表示し、POST /login で資格情報を確かめてセッション Cookie を発行する
+func handleLogin(w http.ResponseWriter, r *http.Request) {
+	next := localRedirect(r.FormValue("next"))
+	if !authEnabled() {
+		http.Redirect(w, r, next, http.StatusSeeOther)
+		return
+	}
+	data := struct{ Error, Next, CSRF string }{Next: next, CSRF: csrfToken(r)}
+	if r.Method == http.MethodPost {
+		user := r.PostFormValue("user")
+		if authenticate(user, r.PostFormValue("password")) != roleNone {
+			http.SetCookie(w, &http.Cookie{
+				Name:     sessionCookie,
+				Value:    sessions.create(user),
+				Path:     "/",
+				HttpOnly: true,
+				Secure:   r.TLS != nil,
+				SameSite: http.SameSiteStrictMode,
+			})
+			http.Redirect(w, r, next, http.StatusSeeOther)
+			return
+		}
+		data.Error = "ユーザー名かパスワードが違います"
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if data.Error != "" {
+		w.WriteHeader(http.StatusUnauthorized)
+	}
+	loginTmpl.Execute(w, data)
+}
+
+// handleLogout は POST /logout でセッションを消してログインフォームに戻す
+func handleLogout(w http.ResponseWriter, r *http.Request) {
+	if id := sessionID(r); id != "" {
+		sessions.remove(id)
+	}
+	http.SetCookie(w, &http.Cookie{Name: sessionCookie, Path: "/", MaxAge: -1, HttpOnly: true})
+	http.Redirect(w, r, "/login", http.StatusSeeOther)
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// shortLink は /s/<code> から任意の URL へのリダイレクト。ホスト名だけを検査する SSRF の対象に、
+// このサーバーの URL を渡して内部のアドレスへ飛ばすのに使う
+type shortLink struct {
+	Code    string    `json:"code"`
+	URL     string    `json:"url"`
+	Status  int       `json:"status"`
+	Label   string    `json:"label,omitempty"`
+	Created time.Time `json:"created"`
+}
+
+// shortLinkStore は作った短縮 URL の一覧。path が空ならメモリだけに持つ
+type shortLinkStore struct {
+	mu    sync.Mutex
+	path  string
+	links []shortLink
+}
+
+var shortLinks = &shortLinkStore{}
+
+var (
+	shortCodePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+	errShortCodeExists   = errors.New("code already exists")
+	errShortLinkNotFound = errors.New("short link not found")
+)
+
+// load は -shortlink-file を読む。ファイルがなければ空から始める
+func (s *shortLinkStore) load(path string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.path = path
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, &s.links)
+}
+
+// save は呼び出し側でロックを取っておく
+func (s *shortLinkStore) save() error {
+	return writeJSONFile(s.path, s.links)
+}
+
+// create は link.Code が空ならランダムなコードを付けて追加する
+func (s *shortLinkStore) create(link shortLink) (shortLink, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if link.Code == "" {
+		b := make([]byte, 5)
+		rand.Read(b)
+		link.Code = correlationEncoding.EncodeToString(b)
+	}
+	if s.index(link.Code) >= 0 {
+		return shortLink{}, errShortCodeExists
+	}
+	link.Created = time.Now()
+	s.links = append(s.links, link)
+	return link, s.save()
+}
+
+func (s *shortLinkStore) index(code string) int {
+	return slices.IndexFunc(s.links, func(l shortLink) bool { return l.Code == code })
+}
+
+func (s *shortLinkStore) get(code string) (shortLink, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if i := s.index(code); i >= 0 {
+		return s.links[i], true
+	}
+	return shortLink{}, false
+}
+
+func (s *shortLinkStore) list() []shortLink {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.links)
+}
+
+func (s *shortLinkStore) remove(code string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.index(code)
+	if i < 0 {
+		return errShortLinkNotFound
+	}
+	s.links = slices.Delete(s.links, i, i+1)
+	return s.save()
+}
+
+// shortLinkResponse は /s/<code> ならリダイレクトの応答を返す。記録は handleAll が通常どおり行う
+func shortLinkResponse(r *http.Request) *mockResponse {
+	code, ok := strings.CutPrefix(r.URL.Path, "/s/")
+	if !ok {
+		return nil
+	}
+	link, ok := shortLinks.get(code)
+	if !ok {
+		return nil
+	}
+	return &mockResponse{
+		Status: link.Status,
+		Header: http.Header{"Location": {link.URL}},
+		Tags:   []string{"shortlink"},
+	}
+}
+
+// redirectStatus は使えるリダイレクトのステータスか
+func redirectStatus(code int) bool {
+	switch code {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther, http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return true
+	}
+	return false
+}
+
+// handleShortLinks は GET /api/shortlinks で一覧を、POST /api/shortlinks で
+// {"url": "http://169.254.169.254/", "code": "任意", "status": 302, "label": "..."} の短縮 URL を作る
+func handleShortLinks(w http.ResponseWriter, r *http.Request) {
+	type linkWithShort struct {
+		shortLink
+		Short string `json:"short"`
+	}
+	if r.Method == http.MethodGet {
+		links := []linkWithShort{}
+		for _, l := range shortLinks.list() {
+			links = append(links, linkWithShort{l, "http://" + serverDomain + "/s/" + l.Code})
+		}
+		writeJSON(w, links)
+		return
+	}
+	var link shortLink
+	if err := json.NewDecoder(io.LimitReader(r.Body, 8192)).Decode(&link); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !redirectTarget(link.URL) {
+		http.Error(w, "url must be an absolute url", http.StatusBadRequest)
+		return
+	}
+	if link.Code != "" && !shortCodePattern.MatchString(link.Code) {
+		http.Error(w, "code must match "+shortCodePattern.String(), http.StatusBadRequest)
+		return
+	}
+	if link.Status == 0 {
+		link.Status = http.StatusFound
+	}
+	if !redirectStatus(link.Status) {
+		http.Error(w, "status must be 301, 302, 303, 307 or 308", http.StatusBadRequest)
+		return
+	}
+	link.Label = strings.TrimSpace(link.Label)
+	link, err := shortLinks.create(link)
+	if errors.Is(err, errShortCodeExists) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	writeJSON(w, linkWithShort{link, "http://" + serverDomain + "/s/" + link.Code})
+}
+
+// handleDeleteShortLink は DELETE /api/shortlinks/{code}
+func handleDeleteShortLink(w http.ResponseWriter, r *http.Request) {
+	err := shortLinks.remove(r.PathValue("code"))
+	if errors.Is(err, errShortLinkNotFound) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	siemVendor  = "goSsrfServ"
+	siemProduct = "SSRF Monitor"
+	siemVersion = "1.0"
+	// siemMaxMsg は msg に入れる生リクエストの最大バイト数（UDP syslog の 1 パケットに収めるため）
+	siemMaxMsg = 1024
+	// siemSyslogPri は facility local0、severity notice
+	siemSyslogPri = 16*8 + 5
+)
+
+// siemWriter は新しいエントリを CEF か LEEF の 1 行にしてファイルか UDP syslog に書き出す
+type siemWriter struct {
+	mu     sync.Mutex
+	format string
+	out    io.WriteCloser
+	syslog bool // UDP のときは syslog ヘッダーを付けて 1 行 1 パケットで送る
+}
+
+// newSIEMWriter は format に cef か leef、target にファイルパスか udp://host:514 を受け取る
+func newSIEMWriter(format, target string) (*siemWriter, error) {
+	if format != "cef" && format != "leef" {
+		return nil, fmt.Errorf("unknown SIEM format %q (cef or leef)", format)
+	}
+	w := &siemWriter{format: format}
+	if u, err := url.Parse(target); err == nil && u.Scheme == "udp" {
+		conn, err := net.Dial("udp", u.Host)
+		if err != nil {
+			return nil, err
+		}
+		w.out, w.syslog = conn, true
+		return w, nil
+	}
+	f, err := os.OpenFile(target, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+	w.out = f
+	return w, nil
+}
+
+func (w *siemWriter) add(entry LogEntry) {
+	line := cefLine(entry)
+	if w.format == "leef" {
+		line = leefLine(entry)
+	}
+	if w.syslog {
+		line = fmt.Sprintf("<%d>%s %s %s", siemSyslogPri, time.Unix(0, entry.ID).Format(time.Stamp), domainHost(), line)
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, err := io.WriteString(w.out, line+"\n"); err != nil {
+		fmt.Printf("SIEM Error: %v\n", err)
+	}
+}
+
+// siemFields はエントリから CEF/LEEF 共通の項目を取り出す
+type siemFields struct {
+	method, url, host, userAgent string
+	port                         uint16
+}
+
+func entrySIEMFields(entry LogEntry) siemFields {
+	var f siemFields
+	f.port = pcapPorts[entry.Protocol]
+	if req := entryRequest(entry); req != nil {
+		f.method, f.url, f.host, f.userAgent = req.Method, harURL(entry, req), req.Host, req.UserAgent()
+	}
+	return f
+}
+
+func siemMsg(entry LogEntry) string {
+	msg := entry.RawRequest
+	if len(msg) > siemMaxMsg {
+		msg = msg[:siemMaxMsg]
+	}
+	return msg
+}
+
+// cefLine は ArcSight の CEF:0 形式の 1 行を返す。ヘッダーは | と \、拡張は = と \ と改行をエスケープする
+func cefLine(entry LogEntry) string {
+	header := strings.NewReplacer(`\`, `\\`, `|`, `\|`)
+	ext := strings.NewReplacer(`\`, `\\`, `=`, `\=`, "\r\n", `\n`, "\n", `\n`, "\r", `\r`)
+	f := entrySIEMFields(entry)
+
+	pairs := [][2]string{
+		{"rt", strconv.FormatInt(entry.ID/int64(time.Millisecond), 10)},
+		{"src", entry.IP},
+		{"app", entry.Protocol},
+	}
+	if f.port != 0 {
+		pairs = append(pairs, [2]string{"dpt", strconv.Itoa(int(f.port))})
+	}
+	if f.method != "" {
+		pairs = append(pairs, [2]string{"requestMethod", f.method}, [2]string{"request", f.url},
+			[2]string{"dhost", f.host}, [2]string{"requestClientApplication", f.userAgent})
+	}
+	if len(entry.Tags) > 0 {
+		pairs = append(pairs, [2]string{"cs1Label", "tags"}, [2]string{"cs1", strings.Join(entry.Tags, ",")})
+	}
+	pairs = append(pairs, [2]string{"externalId", strconv.FormatInt(entry.ID, 10)}, [2]string{"msg", siemMsg(entry)})
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "CEF:0|%s|%s|%s|%s|%s|5|", siemVendor, siemProduct, siemVersion,
+		header.Replace(entry.Protocol), header.Replace(entry.Protocol+" request from "+entry.IP))
+	for i, p := range pairs {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(p[0] + "=" + ext.Replace(p[1]))
+	}
+	return b.String()
+}
+
+// leefLine は QRadar の LEEF:1.0 形式の 1 行を返す。属性の区切りはタブ
+func leefLine(entry LogEntry) string {
+	header := strings.NewReplacer("|", " ")
+	value := strings.NewReplacer("\t", `\t`, "\r\n", `\n`, "\n", `\n`, "\r", `\r`)
+	f := entrySIEMFields(entry)
+
+	pairs := [][2]string{
+		{"devTime", time.Unix(0, entry.ID).Format("Jan 02 2006 15:04:05")},
+		{"devTimeFormat", "MMM dd yyyy HH:mm:ss"},
+		{"src", entry.IP},
+		{"proto", entry.Protocol},
+		{"sev", "5"},
+	}
+	if f.port != 0 {
+		pairs = append(pairs, [2]string{"dstPort", strconv.Itoa(int(f.port))})
+	}
+	if f.method != "" {
+		pairs = append(pairs, [2]string{"method", f.method}, [2]string{"url", f.url},
+			[2]string{"dstHost", f.host}, [2]string{"userAgent", f.userAgent})
+	}
+	if len(entry.Tags) > 0 {
+		pairs = append(pairs, [2]string{"tags", strings.Join(entry.Tags, ",")})
+	}
+	pairs = append(pairs, [2]string{"externalId", strconv.FormatInt(entry.ID, 10)}, [2]string{"msg", siemMsg(entry)})
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "LEEF:1.0|%s|%s|%s|%s|", siemVendor, siemProduct, siemVersion, header.Replace(entry.Protocol))
+	for i, p := range pairs {
+		if i > 0 {
+			b.WriteByte('\t')
+		}
+		b.WriteString(p[0] + "=" + value.Replace(p[1]))
+	}
+	return b.String()
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// slackEscape は mrkdwn で特別な意味を持つ &, <, > をエスケープする
+var slackEscape = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// newSlackNotifier は Incoming Webhook にエントリごとのメッセージを送る。
+// tmpl があれば、その結果を mrkdwn のテキストとしてそのまま送る
+func newSlackNotifier(webhook string, tmpl *template.Template) *notifier {
+	return newNotifier("Slack", func(entry LogEntry) error {
+		if tmpl != nil {
+			text, err := renderNotify(tmpl, entry)
+			if err != nil {
+				return err
+			}
+			return postJSON(webhook, map[string]any{"text": text})
+		}
+		text := fmt.Sprintf("*%s* from `%s`\n`%s`", strings.ToUpper(entry.Protocol), entry.IP, slackEscape.Replace(entrySummary(entry)))
+		if len(entry.Tags) > 0 {
+			text += "\nTags: " + slackEscape.Replace(strings.Join(entry.Tags, ", "))
+		}
+		text += fmt.Sprintf("\n<%s|管理画面で開く> (%s)", entryAdminURL(entry), entry.Timestamp)
+		return postJSON(webhook, map[string]any{
+			"text": fmt.Sprintf("%s from %s", entry.Protocol, entry.IP), // 通知のプレビュー用
+			"blocks": []map[string]any{
+				{"type": "section", "text": map[string]string{"type": "mrkdwn", "text": text}},
+			},
+		})
+	})
+}
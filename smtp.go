@@ -0,0 +1,52 @@
+package main
+
+import (
+	"net"
+	"strings"
+)
+
+// handleSMTP は HELO/MAIL/RCPT/DATA を受け付けるだけの SMTP サーバーとして振る舞う
+func handleSMTP(conn net.Conn) {
+	s := newSession(conn)
+	defer s.save("smtp")
+
+	s.reply("220 %s ESMTP ready", domainHost())
+	for {
+		line, err := s.readLine()
+		if err != nil {
+			return
+		}
+		cmd, _, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(cmd) {
+		case "HELO":
+			s.reply("250 %s", domainHost())
+		case "EHLO":
+			s.reply("250-%s", domainHost())
+			s.reply("250-8BITMIME")
+			s.reply("250 SIZE 10485760")
+		case "MAIL", "RCPT", "RSET", "NOOP":
+			s.reply("250 OK")
+		case "VRFY":
+			s.reply("252 Cannot VRFY user")
+		case "DATA":
+			s.reply("354 End data with <CR><LF>.<CR><LF>")
+			for {
+				data, err := s.readLine()
+				if err != nil {
+					return
+				}
+				if data == "." {
+					break
+				}
+			}
+			s.reply("250 OK: queued")
+		case "QUIT":
+			s.reply("221 Bye")
+			return
+		case "":
+			continue
+		default:
+			s.reply("502 Command not implemented")
+		}
+	}
+}
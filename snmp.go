@@ -0,0 +1,244 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+var snmpPDUNames = map[byte]string{
+	0xa0: "GetRequest",
+	0xa1: "GetNextRequest",
+	0xa3: "SetRequest",
+	0xa5: "GetBulkRequest",
+}
+
+var snmpVersionNames = map[int]string{0: "v1", 1: "v2c", 3: "v3"}
+
+// snmpSystemOIDs は system グループのうち応答を返す OID
+var snmpSystemOIDs = map[string]func() string{
+	"1.3.6.1.2.1.1.1.0": func() string { return "Linux " + domainHost() + " 5.15.0-91-generic #101-Ubuntu SMP x86_64" },
+	"1.3.6.1.2.1.1.5.0": domainHost,
+}
+
+type snmpVarBind struct {
+	oid   string
+	value berElement
+}
+
+// startSNMPServer は SNMP の UDP リスナーを起動し、コミュニティ文字列と要求された OID を記録する
+func startSNMPServer(port string) {
+	conn, err := net.ListenPacket("udp", ":"+port)
+	if err != nil {
+		fmt.Printf("SNMP Error: %v\n", err)
+		return
+	}
+	defer conn.Close()
+
+	buf := make([]byte, 65535)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			fmt.Printf("SNMP Error: %v\n", err)
+			continue
+		}
+		packet := make([]byte, n)
+		copy(packet, buf[:n])
+
+		clientIP, _, _ := net.SplitHostPort(addr.String())
+		if resp := handleSNMPPacket(packet, clientIP); resp != nil {
+			conn.WriteTo(resp, addr)
+		}
+	}
+}
+
+// handleSNMPPacket はパケットを記録し、v1/v2c の要求には GetResponse を組み立てる
+func handleSNMPPacket(packet []byte, clientIP string) []byte {
+	version, community, pdu, err := parseSNMPMessage(packet)
+	if err != nil {
+		addLog(newLogEntry("snmp", clientIP, fmt.Sprintf("Malformed SNMP packet: %v\n\n%s", err, dumpBytes(packet)), ""))
+		return nil
+	}
+	versionName, ok := snmpVersionNames[version]
+	if !ok {
+		versionName = strconv.Itoa(version)
+	}
+	if version == 3 {
+		// v3 は USM のユーザー名などが暗号化前提の構造になるため、生データのみ残す
+		addLog(newLogEntry("snmp", clientIP, fmt.Sprintf("Version: %s\n\n%s", versionName, dumpBytes(packet)), ""))
+		return nil
+	}
+
+	pduName, ok := snmpPDUNames[pdu.tag]
+	if !ok {
+		pduName = fmt.Sprintf("PDU 0x%02x", pdu.tag)
+	}
+	requestID, binds, err := parseSNMPPDU(pdu.value)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Version: %s\nCommunity: %s\nPDU: %s\nRequest ID: %d\n", versionName, community, pduName, requestID)
+	for _, vb := range binds {
+		if vb.value.tag == 0x05 {
+			fmt.Fprintf(&b, "OID: %s\n", vb.oid)
+		} else {
+			fmt.Fprintf(&b, "OID: %s = %s\n", vb.oid, snmpValueString(vb.value))
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(&b, "\nDecode error: %v\n%s", err, dumpBytes(packet))
+		addLog(newLogEntry("snmp", clientIP, b.String(), ""))
+		return nil
+	}
+
+	resp, answer := buildSNMPResponse(version, community, requestID, binds)
+	addLog(newLogEntry("snmp", clientIP, b.String(), answer))
+	return resp
+}
+
+func parseSNMPMessage(packet []byte) (version int, community string, pdu berElement, err error) {
+	msg, _, err := berNext(packet)
+	if err != nil {
+		return
+	}
+	if msg.tag != 0x30 {
+		err = errors.New("not a SEQUENCE")
+		return
+	}
+	elem, rest, err := berNext(msg.value)
+	if err != nil {
+		return
+	}
+	version = berParseInt(elem.value)
+	if version == 3 {
+		return
+	}
+	if elem, rest, err = berNext(rest); err != nil {
+		return
+	}
+	community = string(elem.value)
+	pdu, _, err = berNext(rest)
+	return
+}
+
+// parseSNMPPDU は request-id と varbind の一覧を取り出す（GetBulk の non-repeaters なども同じ位置にある）
+func parseSNMPPDU(b []byte) (int, []snmpVarBind, error) {
+	elem, rest, err := berNext(b)
+	if err != nil {
+		return 0, nil, err
+	}
+	requestID := berParseInt(elem.value)
+	for range 2 {
+		if _, rest, err = berNext(rest); err != nil {
+			return requestID, nil, err
+		}
+	}
+	list, _, err := berNext(rest)
+	if err != nil {
+		return requestID, nil, err
+	}
+
+	var binds []snmpVarBind
+	rest = list.value
+	for len(rest) > 0 {
+		var seq, oid, value berElement
+		if seq, rest, err = berNext(rest); err != nil {
+			return requestID, binds, err
+		}
+		inner := seq.value
+		if oid, inner, err = berNext(inner); err != nil {
+			return requestID, binds, err
+		}
+		if value, _, err = berNext(inner); err != nil {
+			return requestID, binds, err
+		}
+		binds = append(binds, snmpVarBind{oid: decodeOID(oid.value), value: value})
+	}
+	return requestID, binds, nil
+}
+
+// buildSNMPResponse は既知の OID に値を、それ以外には v1 なら noSuchName、v2c なら noSuchObject を返す
+func buildSNMPResponse(version int, community string, requestID int, binds []snmpVarBind) ([]byte, string) {
+	var list []byte
+	var answer strings.Builder
+	errorStatus, errorIndex := 0, 0
+	for i, vb := range binds {
+		var value []byte
+		if f, ok := snmpSystemOIDs[vb.oid]; ok {
+			s := f()
+			value = berEncode(0x04, []byte(s))
+			fmt.Fprintf(&answer, "%s = %q\n", vb.oid, s)
+		} else if version == 0 {
+			value = berEncode(0x05, nil)
+			if errorStatus == 0 {
+				errorStatus, errorIndex = 2, i+1
+			}
+			fmt.Fprintf(&answer, "%s = noSuchName\n", vb.oid)
+		} else {
+			value = berEncode(0x80, nil)
+			fmt.Fprintf(&answer, "%s = noSuchObject\n", vb.oid)
+		}
+		list = append(list, berEncode(0x30, append(berEncode(0x06, encodeOID(vb.oid)), value...))...)
+	}
+
+	pdu := berEncode(0x02, berInt(requestID))
+	pdu = append(pdu, berEncode(0x02, berInt(errorStatus))...)
+	pdu = append(pdu, berEncode(0x02, berInt(errorIndex))...)
+	pdu = append(pdu, berEncode(0x30, list)...)
+
+	msg := berEncode(0x02, berInt(version))
+	msg = append(msg, berEncode(0x04, []byte(community))...)
+	msg = append(msg, berEncode(0xa2, pdu)...)
+	return berEncode(0x30, msg), answer.String()
+}
+
+func snmpValueString(v berElement) string {
+	switch v.tag {
+	case 0x02, 0x41, 0x42, 0x43:
+		return strconv.Itoa(berParseInt(v.value))
+	case 0x04:
+		return strconv.Quote(string(v.value))
+	case 0x06:
+		return decodeOID(v.value)
+	case 0x40:
+		return net.IP(v.value).String()
+	}
+	return fmt.Sprintf("[0x%02x] %x", v.tag, v.value)
+}
+
+func decodeOID(b []byte) string {
+	if len(b) == 0 {
+		return ""
+	}
+	parts := []string{strconv.Itoa(int(b[0]) / 40), strconv.Itoa(int(b[0]) % 40)}
+	v := 0
+	for _, c := range b[1:] {
+		v = v<<7 | int(c&0x7f)
+		if c&0x80 == 0 {
+			parts = append(parts, strconv.Itoa(v))
+			v = 0
+		}
+	}
+	return strings.Join(parts, ".")
+}
+
+func encodeOID(oid string) []byte {
+	var nums []int
+	for _, p := range strings.Split(oid, ".") {
+		n, _ := strconv.Atoi(p)
+		nums = append(nums, n)
+	}
+	if len(nums) < 2 {
+		return nil
+	}
+	out := []byte{byte(nums[0]*40 + nums[1])}
+	for _, n := range nums[2:] {
+		chunk := []byte{byte(n & 0x7f)}
+		for n >>= 7; n > 0; n >>= 7 {
+			chunk = append([]byte{byte(n&0x7f) | 0x80}, chunk...)
+		}
+		out = append(out, chunk...)
+	}
+	return out
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// openSQLiteStore はデータベースを開き、なければテーブルを作る
+func openSQLiteStore(path string) (*sqlStore, error) {
+	db, err := sql.Open("sqlite3", path+"?_journal_mode=WAL&_busy_timeout=5000")
+	if err != nil {
+		return nil, err
+	}
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS logs (
+		id        INTEGER PRIMARY KEY,
+		timestamp TEXT NOT NULL,
+		protocol  TEXT NOT NULL,
+		ip        TEXT NOT NULL,
+		archived  INTEGER NOT NULL DEFAULT 0,
+		starred   INTEGER NOT NULL DEFAULT 0,
+		read      INTEGER NOT NULL DEFAULT 0,
+		entry     TEXT NOT NULL
+	)`)
+	if err == nil {
+		err = addSQLiteStateColumns(db)
+	}
+	if err == nil {
+		_, err = db.Exec(`CREATE INDEX IF NOT EXISTS logs_state_idx ON logs (archived, read, starred, id)`)
+	}
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &sqlStore{db: db}, nil
+}
+
+// addSQLiteStateColumns は状態の列がない古いデータベースに列を足し、既存の行を entry から埋める
+func addSQLiteStateColumns(db *sql.DB) error {
+	for _, col := range []string{"archived", "starred", "read"} {
+		var n int
+		if err := db.QueryRow(`SELECT COUNT(*) FROM pragma_table_info('logs') WHERE name = ?`, col).Scan(&n); err != nil {
+			return err
+		}
+		if n > 0 {
+			continue
+		}
+		if _, err := db.Exec(fmt.Sprintf(`ALTER TABLE logs ADD COLUMN %s INTEGER NOT NULL DEFAULT 0`, col)); err != nil {
+			return err
+		}
+		if _, err := db.Exec(fmt.Sprintf(`UPDATE logs SET %[1]s = COALESCE(json_extract(entry, '$.%[1]s'), 0)`, col)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// sqlStore は SQLite と PostgreSQL で共通の logs テーブルを扱う。
+// エントリは JSON のまま entry 列に入れ、検索に使いそうな列と一覧の絞り込みに使う状態の列だけ別に持つ
+type sqlStore struct {
+	db *sql.DB
+	// dollar が true ならプレースホルダを PostgreSQL 形式（$1, $2, ...）に書き換える
+	dollar bool
+}
+
+func (s *sqlStore) rebind(query string) string {
+	if !s.dollar {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	for _, c := range query {
+		if c == '?' {
+			n++
+			fmt.Fprintf(&b, "$%d", n)
+			continue
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+func (s *sqlStore) exec(query string, args ...any) (sql.Result, error) {
+	return s.db.Exec(s.rebind(query), args...)
+}
+
+func (s *sqlStore) Append(entry LogEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	res, err := s.exec(`INSERT INTO logs (id, timestamp, protocol, ip, archived, starred, read, entry) VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO NOTHING`,
+		entry.ID, entry.Timestamp, entry.Protocol, entry.IP, entry.Archived, entry.Starred, entry.Read, string(data))
+	if err != nil {
+		return err
+	}
+	// 共有の DB では他のインスタンスが同じ ID を先に使っていることがある。上書きせずに呼び出し元に振り直させる
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return errDuplicateID
+	}
+	return nil
+}
+
+func (s *sqlStore) List(limit int) ([]LogEntry, error) {
+	return s.Page(0, limit)
+}
+
+// noLimit は limit が 0 以下のときに LIMIT に渡す値。SQLite の -1 は PostgreSQL では使えないため
+const noLimit = math.MaxInt64
+
+func (s *sqlStore) Page(offset, limit int) ([]LogEntry, error) {
+	n := int64(limit)
+	if limit <= 0 {
+		n = noLimit
+	}
+	return s.query(`SELECT entry FROM logs ORDER BY id DESC LIMIT ? OFFSET ?`, n, max(offset, 0))
+}
+
+func (s *sqlStore) Since(id int64, limit int) ([]LogEntry, error) {
+	n := int64(limit)
+	if limit <= 0 {
+		n = noLimit
+	}
+	return s.query(`SELECT entry FROM logs WHERE id > ? ORDER BY id LIMIT ?`, id, n)
+}
+
+func (s *sqlStore) Count() (int, error) {
+	var n int
+	err := s.db.QueryRow(`SELECT COUNT(*) FROM logs`).Scan(&n)
+	return n, err
+}
+
+func (s *sqlStore) Each(fn func(LogEntry) bool) error {
+	return s.each(fn, `SELECT entry FROM logs ORDER BY id DESC`)
+}
+
+// stateWhere は f を状態の列の条件にする。条件がなければ空
+func stateWhere(f stateFilter) (string, []any) {
+	var conds []string
+	var args []any
+	for _, c := range []struct{ col, want string }{{"archived", f.archived}, {"starred", f.starred}, {"read", f.read}} {
+		if c.want != "" {
+			conds = append(conds, c.col+" = ?")
+			args = append(args, c.want == "1")
+		}
+	}
+	if len(conds) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conds, " AND "), args
+}
+
+func (s *sqlStore) CountState(f stateFilter) (int, error) {
+	where, args := stateWhere(f)
+	var n int
+	err := s.db.QueryRow(s.rebind(`SELECT COUNT(*) FROM logs`+where), args...).Scan(&n)
+	return n, err
+}
+
+func (s *sqlStore) PageState(f stateFilter, offset, limit int) ([]LogEntry, error) {
+	n := int64(limit)
+	if limit <= 0 {
+		n = noLimit
+	}
+	where, args := stateWhere(f)
+	return s.query(`SELECT entry FROM logs`+where+` ORDER BY id DESC LIMIT ? OFFSET ?`, append(args, n, max(offset, 0))...)
+}
+
+// query は entry 列を 1 列だけ選ぶ query を実行し、読めない行を飛ばして返す
+func (s *sqlStore) query(query string, args ...any) ([]LogEntry, error) {
+	var logs []LogEntry
+	err := s.each(func(entry LogEntry) bool {
+		logs = append(logs, entry)
+		return true
+	}, query, args...)
+	return logs, err
+}
+
+// each は query の結果を 1 行ずつ fn に渡し、fn が false を返したら止める
+func (s *sqlStore) each(fn func(LogEntry) bool, query string, args ...any) error {
+	rows, err := s.db.Query(s.rebind(query), args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var data []byte
+		if err := rows.Scan(&data); err != nil {
+			return err
+		}
+		var entry LogEntry
+		if err := json.Unmarshal(data, &entry); err != nil {
+			fmt.Printf("DB Error: skipping broken entry: %v\n", err)
+			continue
+		}
+		if !fn(entry) {
+			break
+		}
+	}
+	return rows.Err()
+}
+
+func (s *sqlStore) Get(id int64) (LogEntry, bool, error) {
+	var data []byte
+	err := s.db.QueryRow(s.rebind(`SELECT entry FROM logs WHERE id = ?`), id).Scan(&data)
+	if err == sql.ErrNoRows {
+		return LogEntry{}, false, nil
+	}
+	if err != nil {
+		return LogEntry{}, false, err
+	}
+	var entry LogEntry
+	err = json.Unmarshal(data, &entry)
+	return entry, err == nil, err
+}
+
+func (s *sqlStore) Update(entry LogEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	_, err = s.exec(`UPDATE logs SET archived = ?, starred = ?, read = ?, entry = ? WHERE id = ?`,
+		entry.Archived, entry.Starred, entry.Read, string(data), entry.ID)
+	return err
+}
+
+func (s *sqlStore) Delete(id int64) error {
+	_, err := s.exec(`DELETE FROM logs WHERE id = ?`, id)
+	return err
+}
+
+func (s *sqlStore) Clear() error {
+	_, err := s.exec(`DELETE FROM logs`)
+	return err
+}
+
+func (s *sqlStore) Prune(keep int, before time.Time) (int, error) {
+	total := 0
+	if !before.IsZero() {
+		res, err := s.exec(`DELETE FROM logs WHERE id < ?`, before.UnixNano())
+		if err != nil {
+			return total, err
+		}
+		n, _ := res.RowsAffected()
+		total += int(n)
+	}
+	if keep > 0 {
+		res, err := s.exec(`DELETE FROM logs WHERE id NOT IN (SELECT id FROM logs ORDER BY id DESC LIMIT ?)`, keep)
+		if err != nil {
+			return total, err
+		}
+		n, _ := res.RowsAffected()
+		total += int(n)
+	}
+	return total, nil
+}
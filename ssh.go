@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+var errSSHDenied = errors.New("permission denied")
+
+// newSSHHostKey は起動ごとにメモリ上でホスト鍵を生成する
+func newSSHHostKey() (ssh.Signer, error) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return ssh.NewSignerFromKey(key)
+}
+
+// teeConn は読み込んだバイトを captureBuffer にも書き込む
+type teeConn struct {
+	net.Conn
+	r io.Reader
+}
+
+func (c teeConn) Read(b []byte) (int, error) {
+	return c.r.Read(b)
+}
+
+// handleSSH は OpenSSH を装ってバージョン交換と認証を受け、クライアントのバージョンと認証試行を記録して切断する
+func handleSSH(conn net.Conn, hostKey ssh.Signer, banner string) {
+	var received captureBuffer
+	var attempts []string
+	var clientVersion string
+
+	config := &ssh.ServerConfig{
+		ServerVersion: banner,
+		MaxAuthTries:  6,
+		PasswordCallback: func(meta ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+			attempts = append(attempts, fmt.Sprintf("user=%q method=password password=%q", meta.User(), password))
+			return nil, errSSHDenied
+		},
+		PublicKeyCallback: func(meta ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			attempts = append(attempts, fmt.Sprintf("user=%q method=publickey key=%s %s", meta.User(), key.Type(), ssh.FingerprintSHA256(key)))
+			return nil, errSSHDenied
+		},
+		KeyboardInteractiveCallback: func(meta ssh.ConnMetadata, challenge ssh.KeyboardInteractiveChallenge) (*ssh.Permissions, error) {
+			answers, err := challenge(meta.User(), "", []string{"Password: "}, []bool{false})
+			if err == nil {
+				attempts = append(attempts, fmt.Sprintf("user=%q method=keyboard-interactive answers=%q", meta.User(), answers))
+			}
+			return nil, errSSHDenied
+		},
+		AuthLogCallback: func(meta ssh.ConnMetadata, method string, err error) {
+			clientVersion = string(meta.ClientVersion())
+			if method == "none" {
+				attempts = append(attempts, fmt.Sprintf("user=%q method=none", meta.User()))
+			}
+		},
+	}
+	config.AddHostKey(hostKey)
+
+	_, _, _, err := ssh.NewServerConn(teeConn{Conn: conn, r: io.TeeReader(conn, &received)}, config)
+
+	// バージョン交換前に切断された場合は、受信した先頭行をクライアントのバージョンとみなす
+	if clientVersion == "" {
+		line, _, _ := bytes.Cut(received.Bytes(), []byte("\n"))
+		clientVersion = strings.TrimRight(string(line), "\r")
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Client version: %s\n", clientVersion)
+	if len(attempts) > 0 {
+		b.WriteString("\nAuth attempts:\n")
+		for _, a := range attempts {
+			b.WriteString("  " + a + "\n")
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(&b, "\nDisconnect: %v\n", err)
+	}
+	addLog(newLogEntry("ssh", remoteIP(conn), b.String(), banner))
+}
@@ -0,0 +1,594 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        v5.28.3
+// source: ssrf.proto
+
+package ssrfpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type LogEntry struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Timestamp     string                 `protobuf:"bytes,2,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	Protocol      string                 `protobuf:"bytes,3,opt,name=protocol,proto3" json:"protocol,omitempty"`
+	ProtoVersion  string                 `protobuf:"bytes,4,opt,name=proto_version,json=protoVersion,proto3" json:"proto_version,omitempty"`
+	ParentId      int64                  `protobuf:"varint,5,opt,name=parent_id,json=parentId,proto3" json:"parent_id,omitempty"`
+	Ip            string                 `protobuf:"bytes,6,opt,name=ip,proto3" json:"ip,omitempty"`
+	RawRequest    string                 `protobuf:"bytes,7,opt,name=raw_request,json=rawRequest,proto3" json:"raw_request,omitempty"`
+	RawResponse   string                 `protobuf:"bytes,8,opt,name=raw_response,json=rawResponse,proto3" json:"raw_response,omitempty"`
+	Tags          []string               `protobuf:"bytes,9,rep,name=tags,proto3" json:"tags,omitempty"`
+	Tls           *TLSInfo               `protobuf:"bytes,10,opt,name=tls,proto3" json:"tls,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LogEntry) Reset() {
+	*x = LogEntry{}
+	mi := &file_ssrf_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LogEntry) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LogEntry) ProtoMessage() {}
+
+func (x *LogEntry) ProtoReflect() protoreflect.Message {
+	mi := &file_ssrf_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LogEntry.ProtoReflect.Descriptor instead.
+func (*LogEntry) Descriptor() ([]byte, []int) {
+	return file_ssrf_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *LogEntry) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *LogEntry) GetTimestamp() string {
+	if x != nil {
+		return x.Timestamp
+	}
+	return ""
+}
+
+func (x *LogEntry) GetProtocol() string {
+	if x != nil {
+		return x.Protocol
+	}
+	return ""
+}
+
+func (x *LogEntry) GetProtoVersion() string {
+	if x != nil {
+		return x.ProtoVersion
+	}
+	return ""
+}
+
+func (x *LogEntry) GetParentId() int64 {
+	if x != nil {
+		return x.ParentId
+	}
+	return 0
+}
+
+func (x *LogEntry) GetIp() string {
+	if x != nil {
+		return x.Ip
+	}
+	return ""
+}
+
+func (x *LogEntry) GetRawRequest() string {
+	if x != nil {
+		return x.RawRequest
+	}
+	return ""
+}
+
+func (x *LogEntry) GetRawResponse() string {
+	if x != nil {
+		return x.RawResponse
+	}
+	return ""
+}
+
+func (x *LogEntry) GetTags() []string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+func (x *LogEntry) GetTls() *TLSInfo {
+	if x != nil {
+		return x.Tls
+	}
+	return nil
+}
+
+type TLSInfo struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Sni           string                 `protobuf:"bytes,1,opt,name=sni,proto3" json:"sni,omitempty"`
+	Version       string                 `protobuf:"bytes,2,opt,name=version,proto3" json:"version,omitempty"`
+	CipherSuite   string                 `protobuf:"bytes,3,opt,name=cipher_suite,json=cipherSuite,proto3" json:"cipher_suite,omitempty"`
+	Alpn          string                 `protobuf:"bytes,4,opt,name=alpn,proto3" json:"alpn,omitempty"`
+	Ja3           string                 `protobuf:"bytes,5,opt,name=ja3,proto3" json:"ja3,omitempty"`
+	Ja3Hash       string                 `protobuf:"bytes,6,opt,name=ja3_hash,json=ja3Hash,proto3" json:"ja3_hash,omitempty"`
+	Ja3S          string                 `protobuf:"bytes,7,opt,name=ja3s,proto3" json:"ja3s,omitempty"`
+	Ja3SHash      string                 `protobuf:"bytes,8,opt,name=ja3s_hash,json=ja3sHash,proto3" json:"ja3s_hash,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TLSInfo) Reset() {
+	*x = TLSInfo{}
+	mi := &file_ssrf_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TLSInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TLSInfo) ProtoMessage() {}
+
+func (x *TLSInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_ssrf_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TLSInfo.ProtoReflect.Descriptor instead.
+func (*TLSInfo) Descriptor() ([]byte, []int) {
+	return file_ssrf_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *TLSInfo) GetSni() string {
+	if x != nil {
+		return x.Sni
+	}
+	return ""
+}
+
+func (x *TLSInfo) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+func (x *TLSInfo) GetCipherSuite() string {
+	if x != nil {
+		return x.CipherSuite
+	}
+	return ""
+}
+
+func (x *TLSInfo) GetAlpn() string {
+	if x != nil {
+		return x.Alpn
+	}
+	return ""
+}
+
+func (x *TLSInfo) GetJa3() string {
+	if x != nil {
+		return x.Ja3
+	}
+	return ""
+}
+
+func (x *TLSInfo) GetJa3Hash() string {
+	if x != nil {
+		return x.Ja3Hash
+	}
+	return ""
+}
+
+func (x *TLSInfo) GetJa3S() string {
+	if x != nil {
+		return x.Ja3S
+	}
+	return ""
+}
+
+func (x *TLSInfo) GetJa3SHash() string {
+	if x != nil {
+		return x.Ja3SHash
+	}
+	return ""
+}
+
+type ListLogsRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Ip    string                 `protobuf:"bytes,1,opt,name=ip,proto3" json:"ip,omitempty"`
+	// HTTP のパスの前方一致
+	Path string `protobuf:"bytes,2,opt,name=path,proto3" json:"path,omitempty"`
+	// UNIX 秒。0 なら指定なし
+	SinceUnix     int64 `protobuf:"varint,3,opt,name=since_unix,json=sinceUnix,proto3" json:"since_unix,omitempty"`
+	Limit         int32 `protobuf:"varint,4,opt,name=limit,proto3" json:"limit,omitempty"`
+	Offset        int32 `protobuf:"varint,5,opt,name=offset,proto3" json:"offset,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListLogsRequest) Reset() {
+	*x = ListLogsRequest{}
+	mi := &file_ssrf_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListLogsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListLogsRequest) ProtoMessage() {}
+
+func (x *ListLogsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_ssrf_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListLogsRequest.ProtoReflect.Descriptor instead.
+func (*ListLogsRequest) Descriptor() ([]byte, []int) {
+	return file_ssrf_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *ListLogsRequest) GetIp() string {
+	if x != nil {
+		return x.Ip
+	}
+	return ""
+}
+
+func (x *ListLogsRequest) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *ListLogsRequest) GetSinceUnix() int64 {
+	if x != nil {
+		return x.SinceUnix
+	}
+	return 0
+}
+
+func (x *ListLogsRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *ListLogsRequest) GetOffset() int32 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+type ListLogsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Total         int32                  `protobuf:"varint,1,opt,name=total,proto3" json:"total,omitempty"`
+	Logs          []*LogEntry            `protobuf:"bytes,2,rep,name=logs,proto3" json:"logs,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListLogsResponse) Reset() {
+	*x = ListLogsResponse{}
+	mi := &file_ssrf_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListLogsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListLogsResponse) ProtoMessage() {}
+
+func (x *ListLogsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_ssrf_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListLogsResponse.ProtoReflect.Descriptor instead.
+func (*ListLogsResponse) Descriptor() ([]byte, []int) {
+	return file_ssrf_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *ListLogsResponse) GetTotal() int32 {
+	if x != nil {
+		return x.Total
+	}
+	return 0
+}
+
+func (x *ListLogsResponse) GetLogs() []*LogEntry {
+	if x != nil {
+		return x.Logs
+	}
+	return nil
+}
+
+type WatchLogsRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// 0 なら呼び出した時点より後のものだけを送る
+	SinceId       int64 `protobuf:"varint,1,opt,name=since_id,json=sinceId,proto3" json:"since_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchLogsRequest) Reset() {
+	*x = WatchLogsRequest{}
+	mi := &file_ssrf_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchLogsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchLogsRequest) ProtoMessage() {}
+
+func (x *WatchLogsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_ssrf_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchLogsRequest.ProtoReflect.Descriptor instead.
+func (*WatchLogsRequest) Descriptor() ([]byte, []int) {
+	return file_ssrf_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *WatchLogsRequest) GetSinceId() int64 {
+	if x != nil {
+		return x.SinceId
+	}
+	return 0
+}
+
+type ClearLogsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ClearLogsRequest) Reset() {
+	*x = ClearLogsRequest{}
+	mi := &file_ssrf_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ClearLogsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ClearLogsRequest) ProtoMessage() {}
+
+func (x *ClearLogsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_ssrf_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ClearLogsRequest.ProtoReflect.Descriptor instead.
+func (*ClearLogsRequest) Descriptor() ([]byte, []int) {
+	return file_ssrf_proto_rawDescGZIP(), []int{5}
+}
+
+type ClearLogsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ClearLogsResponse) Reset() {
+	*x = ClearLogsResponse{}
+	mi := &file_ssrf_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ClearLogsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ClearLogsResponse) ProtoMessage() {}
+
+func (x *ClearLogsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_ssrf_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ClearLogsResponse.ProtoReflect.Descriptor instead.
+func (*ClearLogsResponse) Descriptor() ([]byte, []int) {
+	return file_ssrf_proto_rawDescGZIP(), []int{6}
+}
+
+var File_ssrf_proto protoreflect.FileDescriptor
+
+const file_ssrf_proto_rawDesc = "" +
+	"\n" +
+	"\n" +
+	"ssrf.proto\x12\x0essrfmonitor.v1\"\xa9\x02\n" +
+	"\bLogEntry\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12\x1c\n" +
+	"\ttimestamp\x18\x02 \x01(\tR\ttimestamp\x12\x1a\n" +
+	"\bprotocol\x18\x03 \x01(\tR\bprotocol\x12#\n" +
+	"\rproto_version\x18\x04 \x01(\tR\fprotoVersion\x12\x1b\n" +
+	"\tparent_id\x18\x05 \x01(\x03R\bparentId\x12\x0e\n" +
+	"\x02ip\x18\x06 \x01(\tR\x02ip\x12\x1f\n" +
+	"\vraw_request\x18\a \x01(\tR\n" +
+	"rawRequest\x12!\n" +
+	"\fraw_response\x18\b \x01(\tR\vrawResponse\x12\x12\n" +
+	"\x04tags\x18\t \x03(\tR\x04tags\x12)\n" +
+	"\x03tls\x18\n" +
+	" \x01(\v2\x17.ssrfmonitor.v1.TLSInfoR\x03tls\"\xca\x01\n" +
+	"\aTLSInfo\x12\x10\n" +
+	"\x03sni\x18\x01 \x01(\tR\x03sni\x12\x18\n" +
+	"\aversion\x18\x02 \x01(\tR\aversion\x12!\n" +
+	"\fcipher_suite\x18\x03 \x01(\tR\vcipherSuite\x12\x12\n" +
+	"\x04alpn\x18\x04 \x01(\tR\x04alpn\x12\x10\n" +
+	"\x03ja3\x18\x05 \x01(\tR\x03ja3\x12\x19\n" +
+	"\bja3_hash\x18\x06 \x01(\tR\aja3Hash\x12\x12\n" +
+	"\x04ja3s\x18\a \x01(\tR\x04ja3s\x12\x1b\n" +
+	"\tja3s_hash\x18\b \x01(\tR\bja3sHash\"\x82\x01\n" +
+	"\x0fListLogsRequest\x12\x0e\n" +
+	"\x02ip\x18\x01 \x01(\tR\x02ip\x12\x12\n" +
+	"\x04path\x18\x02 \x01(\tR\x04path\x12\x1d\n" +
+	"\n" +
+	"since_unix\x18\x03 \x01(\x03R\tsinceUnix\x12\x14\n" +
+	"\x05limit\x18\x04 \x01(\x05R\x05limit\x12\x16\n" +
+	"\x06offset\x18\x05 \x01(\x05R\x06offset\"V\n" +
+	"\x10ListLogsResponse\x12\x14\n" +
+	"\x05total\x18\x01 \x01(\x05R\x05total\x12,\n" +
+	"\x04logs\x18\x02 \x03(\v2\x18.ssrfmonitor.v1.LogEntryR\x04logs\"-\n" +
+	"\x10WatchLogsRequest\x12\x19\n" +
+	"\bsince_id\x18\x01 \x01(\x03R\asinceId\"\x12\n" +
+	"\x10ClearLogsRequest\"\x13\n" +
+	"\x11ClearLogsResponse2\xf5\x01\n" +
+	"\aMonitor\x12M\n" +
+	"\bListLogs\x12\x1f.ssrfmonitor.v1.ListLogsRequest\x1a .ssrfmonitor.v1.ListLogsResponse\x12I\n" +
+	"\tWatchLogs\x12 .ssrfmonitor.v1.WatchLogsRequest\x1a\x18.ssrfmonitor.v1.LogEntry0\x01\x12P\n" +
+	"\tClearLogs\x12 .ssrfmonitor.v1.ClearLogsRequest\x1a!.ssrfmonitor.v1.ClearLogsResponseB\x18Z\x16go-ssrf-monitor/ssrfpbb\x06proto3"
+
+var (
+	file_ssrf_proto_rawDescOnce sync.Once
+	file_ssrf_proto_rawDescData []byte
+)
+
+func file_ssrf_proto_rawDescGZIP() []byte {
+	file_ssrf_proto_rawDescOnce.Do(func() {
+		file_ssrf_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_ssrf_proto_rawDesc), len(file_ssrf_proto_rawDesc)))
+	})
+	return file_ssrf_proto_rawDescData
+}
+
+var file_ssrf_proto_msgTypes = make([]protoimpl.MessageInfo, 7)
+var file_ssrf_proto_goTypes = []any{
+	(*LogEntry)(nil),          // 0: ssrfmonitor.v1.LogEntry
+	(*TLSInfo)(nil),           // 1: ssrfmonitor.v1.TLSInfo
+	(*ListLogsRequest)(nil),   // 2: ssrfmonitor.v1.ListLogsRequest
+	(*ListLogsResponse)(nil),  // 3: ssrfmonitor.v1.ListLogsResponse
+	(*WatchLogsRequest)(nil),  // 4: ssrfmonitor.v1.WatchLogsRequest
+	(*ClearLogsRequest)(nil),  // 5: ssrfmonitor.v1.ClearLogsRequest
+	(*ClearLogsResponse)(nil), // 6: ssrfmonitor.v1.ClearLogsResponse
+}
+var file_ssrf_proto_depIdxs = []int32{
+	1, // 0: ssrfmonitor.v1.LogEntry.tls:type_name -> ssrfmonitor.v1.TLSInfo
+	0, // 1: ssrfmonitor.v1.ListLogsResponse.logs:type_name -> ssrfmonitor.v1.LogEntry
+	2, // 2: ssrfmonitor.v1.Monitor.ListLogs:input_type -> ssrfmonitor.v1.ListLogsRequest
+	4, // 3: ssrfmonitor.v1.Monitor.WatchLogs:input_type -> ssrfmonitor.v1.WatchLogsRequest
+	5, // 4: ssrfmonitor.v1.Monitor.ClearLogs:input_type -> ssrfmonitor.v1.ClearLogsRequest
+	3, // 5: ssrfmonitor.v1.Monitor.ListLogs:output_type -> ssrfmonitor.v1.ListLogsResponse
+	0, // 6: ssrfmonitor.v1.Monitor.WatchLogs:output_type -> ssrfmonitor.v1.LogEntry
+	6, // 7: ssrfmonitor.v1.Monitor.ClearLogs:output_type -> ssrfmonitor.v1.ClearLogsResponse
+	5, // [5:8] is the sub-list for method output_type
+	2, // [2:5] is the sub-list for method input_type
+	2, // [2:2] is the sub-list for extension type_name
+	2, // [2:2] is the sub-list for extension extendee
+	0, // [0:2] is the sub-list for field type_name
+}
+
+func init() { file_ssrf_proto_init() }
+func file_ssrf_proto_init() {
+	if File_ssrf_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_ssrf_proto_rawDesc), len(file_ssrf_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   7,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_ssrf_proto_goTypes,
+		DependencyIndexes: file_ssrf_proto_depIdxs,
+		MessageInfos:      file_ssrf_proto_msgTypes,
+	}.Build()
+	File_ssrf_proto = out.File
+	file_ssrf_proto_goTypes = nil
+	file_ssrf_proto_depIdxs = nil
+}
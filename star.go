@@ -0,0 +1,37 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// handleEntryFlag は /api/logs/{id}/star と /api/logs/{id}/archive を扱う。
+// POST で付け、DELETE で外し、更新後のエントリを返す
+func handleEntryFlag(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+	on := r.Method == http.MethodPost
+	var edit func(entry *LogEntry)
+	switch r.PathValue("flag") {
+	case "star":
+		edit = func(entry *LogEntry) { entry.Starred = on }
+	case "archive":
+		edit = func(entry *LogEntry) { entry.Archived = on }
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	entry, ok, err := editEntry(id, edit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	writeJSON(w, entry)
+}
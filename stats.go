@@ -0,0 +1,86 @@
+package main
+
+import (
+	"net/http"
+	"runtime"
+	"sort"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+const statsDefaultTop = 20
+
+var (
+	startedAt = time.Now()
+	// receivedTotal は起動してから記録した件数。保存先の件数と違い -limit や削除の影響を受けない
+	receivedTotal atomic.Int64
+)
+
+// statsCount は集計の 1 行
+type statsCount struct {
+	Key   string `json:"key"`
+	Count int    `json:"count"`
+}
+
+// handleStats は GET /api/stats?top=20&campaign= で保存されているエントリの集計を返す
+func handleStats(w http.ResponseWriter, r *http.Request) {
+	top, _ := strconv.Atoi(r.URL.Query().Get("top"))
+	if top <= 0 {
+		top = statsDefaultTop
+	}
+	logs, err := campaignLogs(r, 0)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	byPath, byIP, byProtocol, byHour := map[string]int{}, map[string]int{}, map[string]int{}, map[string]int{}
+	for _, entry := range logs {
+		byIP[entry.IP]++
+		byProtocol[entry.Protocol]++
+		byHour[time.Unix(0, entry.ID).Truncate(time.Hour).Format(time.RFC3339)]++
+		if req := entryRequest(entry); req != nil {
+			byPath[req.URL.Path]++
+		}
+	}
+	// 時間ごとの件数は時刻順、それ以外は多い順
+	hours := sortedCounts(byHour, 0)
+	sort.Slice(hours, func(i, j int) bool { return hours[i].Key < hours[j].Key })
+
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	writeJSON(w, map[string]any{
+		"stored":         len(logs),
+		"limit":          maxLogs,
+		"received_total": receivedTotal.Load(),
+		"uptime_seconds": int(time.Since(startedAt).Seconds()),
+		"by_path":        sortedCounts(byPath, top),
+		"by_ip":          sortedCounts(byIP, top),
+		"by_protocol":    sortedCounts(byProtocol, 0),
+		"by_hour":        hours,
+		"memory": map[string]any{
+			"heap_alloc_bytes": mem.HeapAlloc,
+			"sys_bytes":        mem.Sys,
+			"goroutines":       runtime.NumGoroutine(),
+		},
+	})
+}
+
+// sortedCounts は件数の多い順に並べ、top が正ならその件数で切る
+func sortedCounts(m map[string]int, top int) []statsCount {
+	counts := make([]statsCount, 0, len(m))
+	for key, n := range m {
+		counts = append(counts, statsCount{key, n})
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Count != counts[j].Count {
+			return counts[i].Count > counts[j].Count
+		}
+		return counts[i].Key < counts[j].Key
+	})
+	if top > 0 && len(counts) > top {
+		counts = counts[:top]
+	}
+	return counts
+}
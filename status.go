@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// statusResponse は /status/{code} にそのステータスを返す。被害側の HTTP クライアントがステータスごとにどう振る舞うかを見る用。
+// 3xx は ?location=（省略時 /）へ、401 は Basic 認証を求める
+func statusResponse(r *http.Request) *mockResponse {
+	rest, ok := strings.CutPrefix(r.URL.Path, "/status/")
+	if !ok {
+		return nil
+	}
+	code, err := strconv.Atoi(rest)
+	if err != nil || code < 200 || code > 599 {
+		return nil
+	}
+	res := &mockResponse{
+		Status: code,
+		Header: http.Header{},
+		Body:   strings.TrimSpace(fmt.Sprintf("%d %s", code, http.StatusText(code))),
+		Tags:   []string{"status"},
+	}
+	switch {
+	case code == http.StatusNoContent || code == http.StatusNotModified:
+		res.Body = ""
+	case code >= 300 && code < 400:
+		location := r.URL.Query().Get("location")
+		if location == "" {
+			location = "/"
+		}
+		res.Header.Set("Location", location)
+	case code == http.StatusUnauthorized:
+		res.Header.Set("WWW-Authenticate", `Basic realm="ssrf"`)
+	case code == http.StatusTooManyRequests || code == http.StatusServiceUnavailable:
+		res.Header.Set("Retry-After", "1")
+	}
+	return res
+}
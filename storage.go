@@ -0,0 +1,412 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Storage はログの保存先。メモリ、ファイル、SQLite、リモートの DB などを差し替えられるようにする。
+// 一覧は常に新しい順で扱う
+type Storage interface {
+	// Append はエントリを追加する。複数のインスタンスで共有する保存先では、同じ ID が既にあれば何も書かずに errDuplicateID を返す
+	Append(entry LogEntry) error
+	// List は新しい順に最大 limit 件を返す。limit が 0 以下なら全件
+	List(limit int) ([]LogEntry, error)
+	// Page は新しい順に offset 件目から最大 limit 件を返す。limit が 0 以下なら残り全部
+	Page(offset, limit int) ([]LogEntry, error)
+	// Since は ID が id より大きいエントリを古い順に最大 limit 件返す。limit が 0 以下なら全件
+	Since(id int64, limit int) ([]LogEntry, error)
+	Count() (int, error)
+	// Each は新しい順に全エントリを一度だけ辿って fn に渡し、fn が false を返したら止める。fn の中から保存先を変更しない
+	Each(fn func(LogEntry) bool) error
+	// CountState は状態が f に合うエントリの件数を返す
+	CountState(f stateFilter) (int, error)
+	// PageState は状態が f に合うエントリを新しい順に offset 件目から最大 limit 件返す。limit が 0 以下なら残り全部
+	PageState(f stateFilter, offset, limit int) ([]LogEntry, error)
+	Get(id int64) (LogEntry, bool, error)
+	// Update は同じ ID のエントリを置き換える。見つからなければ何もしない
+	Update(entry LogEntry) error
+	Delete(id int64) error
+	Clear() error
+	// Prune は新しい方から keep 件を超えた分と before より古い分を消し、消した件数を返す。
+	// keep が 0 以下、before がゼロ値ならその条件は使わない
+	Prune(keep int, before time.Time) (int, error)
+}
+
+// errDuplicateID は共有の保存先に同じ ID のエントリが既にあったことを表す
+var errDuplicateID = errors.New("duplicate log id")
+
+// store は全リスナーが記録に使う保存先。main で -db などの指定に応じて組み立てる
+var store Storage
+
+// openStorage は -storage の種類に応じて永続化先を開く
+func openStorage(kind, path string) (Storage, error) {
+	switch kind {
+	case "sqlite":
+		return openSQLiteStore(path)
+	case "bolt":
+		return openBoltStore(path)
+	case "postgres":
+		return openPostgresStore(path)
+	}
+	return nil, fmt.Errorf("unknown storage %q (sqlite, bolt or postgres)", kind)
+}
+
+// stateFilter は保存先が索引で答えられる既読・スター・アーカイブの絞り込み。
+// それぞれ "1" ならその状態のものだけ、"0" ならそうでないものだけ、空なら区別しない
+type stateFilter struct {
+	archived string
+	starred  string
+	read     string
+}
+
+func (f stateFilter) match(entry LogEntry) bool {
+	return stateMatch(f.archived, entry.Archived) && stateMatch(f.starred, entry.Starred) && stateMatch(f.read, entry.Read)
+}
+
+func stateMatch(want string, v bool) bool {
+	return want == "" || v == (want == "1")
+}
+
+// countState と pageState は状態の索引を持たない保存先向けに、Each で一度だけ辿って答える
+func countState(s Storage, f stateFilter) (int, error) {
+	n := 0
+	err := s.Each(func(entry LogEntry) bool {
+		if f.match(entry) {
+			n++
+		}
+		return true
+	})
+	return n, err
+}
+
+func pageState(s Storage, f stateFilter, offset, limit int) ([]LogEntry, error) {
+	var logs []LogEntry
+	err := s.Each(func(entry LogEntry) bool {
+		if !f.match(entry) {
+			return true
+		}
+		if offset > 0 {
+			offset--
+			return true
+		}
+		logs = append(logs, entry)
+		return limit <= 0 || len(logs) < limit
+	})
+	return logs, err
+}
+
+// pruned は Prune の条件に当てはまるかを返す。i は新しい方から数えた位置
+func pruned(entry LogEntry, i, keep int, before time.Time) bool {
+	return (keep > 0 && i >= keep) || (!before.IsZero() && entry.ID < before.UnixNano())
+}
+
+// memoryStore は最大 limit 件を新しい順に持つメモリ上の保存先
+type memoryStore struct {
+	mu    sync.RWMutex
+	logs  []LogEntry
+	limit int
+	// onEvict が設定されていれば、上限を超えて押し出されたエントリを渡す
+	onEvict func([]LogEntry)
+}
+
+func newMemoryStore(limit int) *memoryStore {
+	return &memoryStore{limit: limit}
+}
+
+// Append はエントリを先頭に追加し、limit を超えた分を切り捨てる
+func (s *memoryStore) Append(entry LogEntry) error {
+	s.mu.Lock()
+	s.logs = append([]LogEntry{entry}, s.logs...)
+	var evicted []LogEntry
+	if len(s.logs) > s.limit {
+		evicted = s.logs[s.limit:]
+		s.logs = s.logs[:s.limit:s.limit]
+	}
+	s.mu.Unlock()
+
+	if len(evicted) > 0 && s.onEvict != nil {
+		s.onEvict(evicted)
+	}
+	return nil
+}
+
+func (s *memoryStore) List(limit int) ([]LogEntry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if limit <= 0 || limit > len(s.logs) {
+		limit = len(s.logs)
+	}
+	logs := make([]LogEntry, limit)
+	copy(logs, s.logs)
+	return logs, nil
+}
+
+func (s *memoryStore) Page(offset, limit int) ([]LogEntry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	offset = min(max(offset, 0), len(s.logs))
+	end := len(s.logs)
+	if limit > 0 && limit < end-offset {
+		end = offset + limit
+	}
+	return slices.Clone(s.logs[offset:end]), nil
+}
+
+// Since は先頭から id 以下のエントリに当たるまでをたどる。ID は追加した順に増えていく
+func (s *memoryStore) Since(id int64, limit int) ([]LogEntry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	n := 0
+	for n < len(s.logs) && s.logs[n].ID > id {
+		n++
+	}
+	start := 0
+	if limit > 0 && limit < n {
+		start = n - limit
+	}
+	logs := slices.Clone(s.logs[start:n])
+	slices.Reverse(logs)
+	return logs, nil
+}
+
+func (s *memoryStore) Count() (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.logs), nil
+}
+
+func (s *memoryStore) Each(fn func(LogEntry) bool) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, entry := range s.logs {
+		if !fn(entry) {
+			break
+		}
+	}
+	return nil
+}
+
+func (s *memoryStore) CountState(f stateFilter) (int, error) {
+	return countState(s, f)
+}
+
+func (s *memoryStore) PageState(f stateFilter, offset, limit int) ([]LogEntry, error) {
+	return pageState(s, f, offset, limit)
+}
+
+func (s *memoryStore) Get(id int64) (LogEntry, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, entry := range s.logs {
+		if entry.ID == id {
+			return entry, true, nil
+		}
+	}
+	return LogEntry{}, false, nil
+}
+
+func (s *memoryStore) Update(entry LogEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.logs {
+		if s.logs[i].ID == entry.ID {
+			s.logs[i] = entry
+			break
+		}
+	}
+	return nil
+}
+
+func (s *memoryStore) Delete(id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, entry := range s.logs {
+		if entry.ID == id {
+			s.logs = append(s.logs[:i:i], s.logs[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+func (s *memoryStore) Clear() error {
+	s.mu.Lock()
+	s.logs = nil
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *memoryStore) Prune(keep int, before time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	kept := s.logs[:0:0]
+	for i, entry := range s.logs {
+		if !pruned(entry, i, keep, before) {
+			kept = append(kept, entry)
+		}
+	}
+	n := len(s.logs) - len(kept)
+	s.logs = kept
+	return n, nil
+}
+
+// tieredStore はメモリをキャッシュとして手前に置き、書き込みは全てのバックエンドに流す。
+// キャッシュに収まらない読み込みは最初のバックエンドを正とする
+type tieredStore struct {
+	cache    *memoryStore
+	backends []Storage
+	// shared は最初のバックエンドを他のインスタンスと共有していること。
+	// 他のインスタンスの書き込みはキャッシュに載らないので、キャッシュには書くだけで読み込みは全てバックエンドに任せる
+	shared bool
+}
+
+// newTieredStore は最初のバックエンドから直近の limit 件をキャッシュに読み込む
+func newTieredStore(limit int, backends []Storage, shared bool) (*tieredStore, error) {
+	logs, err := backends[0].List(limit)
+	if err != nil {
+		return nil, err
+	}
+	return &tieredStore{cache: &memoryStore{logs: logs, limit: limit}, backends: backends, shared: shared}, nil
+}
+
+// Append は最初のバックエンドが errDuplicateID を返したら、キャッシュにも他のバックエンドにも書かない
+func (s *tieredStore) Append(entry LogEntry) error {
+	err := s.backends[0].Append(entry)
+	if errors.Is(err, errDuplicateID) {
+		return err
+	}
+	s.cache.Append(entry)
+	errs := []error{err}
+	for _, b := range s.backends[1:] {
+		errs = append(errs, b.Append(entry))
+	}
+	return errors.Join(errs...)
+}
+
+func (s *tieredStore) List(limit int) ([]LogEntry, error) {
+	if !s.shared && limit > 0 && limit <= s.cache.limit {
+		return s.cache.List(limit)
+	}
+	return s.backends[0].List(limit)
+}
+
+func (s *tieredStore) Page(offset, limit int) ([]LogEntry, error) {
+	if !s.shared && limit > 0 && offset <= s.cache.limit-limit {
+		return s.cache.Page(offset, limit)
+	}
+	return s.backends[0].Page(offset, limit)
+}
+
+// Since はキャッシュの一番古いものが id 以下ならキャッシュだけで足りる
+func (s *tieredStore) Since(id int64, limit int) ([]LogEntry, error) {
+	s.cache.mu.RLock()
+	cached := !s.shared && len(s.cache.logs) > 0 && s.cache.logs[len(s.cache.logs)-1].ID <= id
+	s.cache.mu.RUnlock()
+	if cached {
+		return s.cache.Since(id, limit)
+	}
+	return s.backends[0].Since(id, limit)
+}
+
+func (s *tieredStore) Count() (int, error) {
+	return s.backends[0].Count()
+}
+
+// Each と状態の絞り込みはキャッシュでは足りないので、最初のバックエンドに任せる
+func (s *tieredStore) Each(fn func(LogEntry) bool) error {
+	return s.backends[0].Each(fn)
+}
+
+func (s *tieredStore) CountState(f stateFilter) (int, error) {
+	return s.backends[0].CountState(f)
+}
+
+func (s *tieredStore) PageState(f stateFilter, offset, limit int) ([]LogEntry, error) {
+	return s.backends[0].PageState(f, offset, limit)
+}
+
+func (s *tieredStore) Get(id int64) (LogEntry, bool, error) {
+	if s.shared {
+		return s.backends[0].Get(id)
+	}
+	if entry, ok, _ := s.cache.Get(id); ok {
+		return entry, true, nil
+	}
+	return s.backends[0].Get(id)
+}
+
+func (s *tieredStore) Update(entry LogEntry) error {
+	s.cache.Update(entry)
+	var errs []error
+	for _, b := range s.backends {
+		errs = append(errs, b.Update(entry))
+	}
+	return errors.Join(errs...)
+}
+
+func (s *tieredStore) Delete(id int64) error {
+	s.cache.Delete(id)
+	var errs []error
+	for _, b := range s.backends {
+		errs = append(errs, b.Delete(id))
+	}
+	return errors.Join(errs...)
+}
+
+func (s *tieredStore) Clear() error {
+	s.cache.Clear()
+	var errs []error
+	for _, b := range s.backends {
+		errs = append(errs, b.Clear())
+	}
+	return errors.Join(errs...)
+}
+
+// Prune は最初のバックエンドで消した件数を返す
+func (s *tieredStore) Prune(keep int, before time.Time) (int, error) {
+	s.cache.Prune(keep, before)
+	total := 0
+	var errs []error
+	for i, b := range s.backends {
+		n, err := b.Prune(keep, before)
+		if i == 0 {
+			total = n
+		}
+		errs = append(errs, err)
+	}
+	return total, errors.Join(errs...)
+}
+
+// editMu は editEntry の読み出しから書き戻しまでを直列にする
+var editMu sync.Mutex
+
+// editEntry は id のエントリを読み出して edit で書き換え、保存先に書き戻す。
+// 見つからなければ false を返す
+func editEntry(id int64, edit func(entry *LogEntry)) (LogEntry, bool, error) {
+	editMu.Lock()
+	defer editMu.Unlock()
+	entry, ok, err := store.Get(id)
+	if err != nil || !ok {
+		return entry, ok, err
+	}
+	edit(&entry)
+	return entry, true, store.Update(entry)
+}
+
+// startRetention は起動直後と一定間隔ごとに、window より古いエントリを全ての保存先から消す
+func startRetention(s Storage, window time.Duration) {
+	interval := min(max(window/10, time.Minute), time.Hour)
+	for {
+		n, err := s.Prune(0, time.Now().Add(-window))
+		if err != nil {
+			fmt.Printf("Retention Error: %v\n", err)
+		} else if n > 0 {
+			fmt.Printf("Retention: pruned %d entries older than %s\n", n, window)
+		}
+		time.Sleep(interval)
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// streamHeartbeat はプロキシに切られないよう、何も来ないときにコメント行を送る間隔
+const streamHeartbeat = 15 * time.Second
+
+// handleStream は GET /api/stream で新しいエントリを Server-Sent Events として流す。
+// 再接続時に Last-Event-ID があれば、それより新しいものを先に送る
+func handleStream(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	ch := hub.subscribe()
+	defer hub.unsubscribe(ch)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+
+	lastID := int64(0)
+	if id, err := strconv.ParseInt(r.Header.Get("Last-Event-ID"), 10, 64); err == nil {
+		missed, _ := entriesSince(id)
+		for _, entry := range missed {
+			writeEvent(w, entry)
+			lastID = entry.ID
+		}
+	}
+	fmt.Fprint(w, ": connected\n\n")
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case entry := <-ch:
+			if entry.ID <= lastID {
+				continue // Last-Event-ID の補完で送った分
+			}
+			writeEvent(w, entry)
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": ping\n\n")
+		case <-r.Context().Done():
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+func writeEvent(w http.ResponseWriter, entry LogEntry) {
+	data, _ := json.Marshal(entry)
+	fmt.Fprintf(w, "id: %d\nevent: entry\ndata: %s\n\n", entry.ID, data)
+}
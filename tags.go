@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const tagMaxLen = 64
+
+// tagRules は -tag-rules で読み込んだ自動タグ付けのルール
+var tagRules []tagRule
+
+// tagRule はルールファイルの 1 行。書式は "<tag> <field> <value>" で、field と value は entryRule の通り。
+// 当てはまったルールのタグを全て付ける（例: acme-q3 host *.acme.example）
+type tagRule struct {
+	tag string
+	entryRule
+}
+
+func loadTagRules(name string) ([]tagRule, error) {
+	var rules []tagRule
+	err := readRuleLines(name, func(n int, parts []string) error {
+		if len(parts) != 3 {
+			return fmt.Errorf("expected \"<tag> <field> <value>\"")
+		}
+		tag, err := normalizeTag(parts[0])
+		if err != nil {
+			return err
+		}
+		rule, err := newEntryRule(parts[1], parts[2])
+		if err != nil {
+			return err
+		}
+		rules = append(rules, tagRule{tag: tag, entryRule: rule})
+		return nil
+	})
+	return rules, err
+}
+
+// applyTagRules は addLog から呼ばれ、保存と通知の前にルールのタグを付ける
+func applyTagRules(entry LogEntry) LogEntry {
+	for _, rule := range tagRules {
+		if !slices.Contains(entry.Tags, rule.tag) && rule.match(entry) {
+			entry.Tags = append(entry.Tags, rule.tag)
+		}
+	}
+	return entry
+}
+
+// normalizeTag は前後の空白を除き、空や空白・カンマを含むタグ、長すぎるタグを拒否する
+func normalizeTag(tag string) (string, error) {
+	tag = strings.TrimSpace(tag)
+	if tag == "" || len(tag) > tagMaxLen || strings.ContainsAny(tag, " \t\r\n,") {
+		return "", fmt.Errorf("invalid tag %q", tag)
+	}
+	return tag, nil
+}
+
+// handleEntryTag は POST /api/logs/{id}/tags/{tag} でタグを付け、DELETE で外す。更新後のエントリを返す
+func handleEntryTag(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+	tag, err := normalizeTag(r.PathValue("tag"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	entry, ok, err := editEntry(id, func(entry *LogEntry) {
+		entry.Tags = slices.DeleteFunc(entry.Tags, func(t string) bool { return t == tag })
+		if r.Method == http.MethodPost {
+			entry.Tags = append(entry.Tags, tag)
+		}
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	writeJSON(w, entry)
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+)
+
+const tcpIdleTimeout = 3 * time.Second
+
+// handleRawTCP は任意プロトコルの接続を受け、先頭 limit バイトを hex ダンプで記録する
+func handleRawTCP(conn net.Conn, limit int, banner string) {
+	if banner != "" {
+		conn.Write([]byte(banner))
+	}
+	data := readUntilIdle(conn, limit)
+
+	_, port, _ := net.SplitHostPort(conn.LocalAddr().String())
+	rawRequest := fmt.Sprintf("Port: %s\nBytes: %d\n\n%s", port, len(data), hex.Dump(data))
+	addLog(newLogEntry("tcp", remoteIP(conn), rawRequest, banner))
+}
+
+// readUntilIdle は limit バイトに達するか、EOF か、一定時間データが来なくなるまで読む
+func readUntilIdle(conn net.Conn, limit int) []byte {
+	data := make([]byte, 0, min(limit, 64*1024))
+	buf := make([]byte, 4096)
+	for len(data) < limit {
+		conn.SetReadDeadline(time.Now().Add(tcpIdleTimeout))
+		n, err := conn.Read(buf[:min(len(buf), limit-len(data))])
+		data = append(data, buf[:n]...)
+		if err != nil {
+			break
+		}
+	}
+	return data
+}
+
+// unescapeBanner は "\r\n" などのエスケープを解釈する。解釈できなければそのまま返す
+func unescapeBanner(s string) string {
+	if u, err := strconv.Unquote(`"` + s + `"`); err == nil {
+		return u
+	}
+	return s
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+	"unicode/utf16"
+)
+
+const (
+	telegramPollTimeout = 50 // getUpdates のロングポーリング秒数
+	telegramLatestMax   = 20
+	telegramTextLimit   = 4096
+	// telegramSummaryMax と telegramTagsMax はエスケープ前に切る文字数。Telegram は UTF-16 で数えるので、
+	// 全部サロゲートペアでも残りの部分と合わせて telegramTextLimit に収まるようにしてある
+	telegramSummaryMax = 1500
+	telegramTagsMax    = 300
+	telegramAPI        = "https://api.telegram.org"
+)
+
+// telegramBot はエントリごとにメッセージを送り、設定したチャットからの /latest に直近のエントリを返す
+type telegramBot struct {
+	token  string
+	chatID string
+	client *http.Client
+}
+
+// newTelegramNotifier は tmpl があれば、その結果を HTML モードのメッセージとして送る
+func newTelegramNotifier(token, chatID string, tmpl *template.Template) *notifier {
+	bot := &telegramBot{token: token, chatID: chatID, client: &http.Client{Timeout: (telegramPollTimeout + 10) * time.Second}}
+	go bot.poll()
+	return newNotifier("Telegram", func(entry LogEntry) error {
+		if tmpl != nil {
+			text, err := renderNotify(tmpl, entry)
+			if err != nil {
+				return err
+			}
+			return bot.sendMessage(text)
+		}
+		return bot.sendMessage(telegramEntryText(entry))
+	})
+}
+
+func telegramEntryText(entry LogEntry) string {
+	text := fmt.Sprintf("<b>%s</b> from <code>%s</code>\n<code>%s</code>",
+		html.EscapeString(strings.ToUpper(entry.Protocol)), html.EscapeString(entry.IP), html.EscapeString(truncateRunes(entrySummary(entry), telegramSummaryMax)))
+	if len(entry.Tags) > 0 {
+		text += "\nTags: " + html.EscapeString(truncateRunes(strings.Join(entry.Tags, ", "), telegramTagsMax))
+	}
+	return text + fmt.Sprintf("\n%s <a href=\"%s\">管理画面</a>", entry.Timestamp, html.EscapeString(entryAdminURL(entry)))
+}
+
+func (b *telegramBot) call(method string, params any, result any) error {
+	resp, err := b.client.Post(telegramAPI+"/bot"+b.token+"/"+method, "application/json", strings.NewReader(mustJSON(params)))
+	if err != nil {
+		// エラーに URL（トークン入り）が含まれるので、メソッド名だけにする
+		return fmt.Errorf("%s: request failed", method)
+	}
+	defer resp.Body.Close()
+	var body struct {
+		OK          bool            `json:"ok"`
+		Description string          `json:"description"`
+		Result      json.RawMessage `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return fmt.Errorf("%s: %s", method, resp.Status)
+	}
+	if !body.OK {
+		return fmt.Errorf("%s: %s", method, body.Description)
+	}
+	if result != nil {
+		return json.Unmarshal(body.Result, result)
+	}
+	return nil
+}
+
+// truncateRunes は s を文字の境界で最大 n 文字に切り、切ったら末尾に … を付ける
+func truncateRunes(s string, n int) string {
+	i := 0
+	for pos := range s {
+		if i == n {
+			return s[:pos] + "…"
+		}
+		i++
+	}
+	return s
+}
+
+// telegramTextLen は Telegram が数える長さ。タグを除き、エンティティを戻した文字列の UTF-16 の単位数
+func telegramTextLen(text string) int {
+	var b strings.Builder
+	inTag := false
+	for _, r := range text {
+		switch {
+		case r == '<':
+			inTag = true
+		case r == '>' && inTag:
+			inTag = false
+		case !inTag:
+			b.WriteRune(r)
+		}
+	}
+	return len(utf16.Encode([]rune(html.UnescapeString(b.String()))))
+}
+
+// sendMessage は text を HTML モードで送る。-telegram-template の結果などで長すぎるときは、
+// 途中で切るとタグの対応が崩れるので、HTML として解釈させずに文字の境界で切って送る
+func (b *telegramBot) sendMessage(text string) error {
+	params := map[string]any{
+		"chat_id":                  b.chatID,
+		"text":                     text,
+		"parse_mode":               "HTML",
+		"disable_web_page_preview": true,
+	}
+	if telegramTextLen(text) > telegramTextLimit {
+		delete(params, "parse_mode")
+		// サロゲートペアは 2 と数えられるので半分にしておく
+		params["text"] = truncateRunes(text, telegramTextLimit/2-1)
+	}
+	return b.call("sendMessage", params, nil)
+}
+
+// poll は getUpdates をロングポーリングし、設定したチャットからのコマンドだけに応える
+func (b *telegramBot) poll() {
+	offset := int64(0)
+	for {
+		var updates []struct {
+			UpdateID int64 `json:"update_id"`
+			Message  *struct {
+				Text string `json:"text"`
+				Chat struct {
+					ID       int64  `json:"id"`
+					Username string `json:"username"`
+				} `json:"chat"`
+			} `json:"message"`
+		}
+		err := b.call("getUpdates", map[string]any{"offset": offset, "timeout": telegramPollTimeout, "allowed_updates": []string{"message"}}, &updates)
+		if err != nil {
+			fmt.Printf("Telegram Error: %v\n", err)
+			time.Sleep(10 * time.Second)
+			continue
+		}
+		for _, u := range updates {
+			offset = u.UpdateID + 1
+			m := u.Message
+			if m == nil || (strconv.FormatInt(m.Chat.ID, 10) != b.chatID && "@"+m.Chat.Username != b.chatID) {
+				continue
+			}
+			b.handleCommand(m.Text)
+		}
+	}
+}
+
+// handleCommand は "/latest" と "/latest 10" に応える（/latest@botname の形も受け付ける）
+func (b *telegramBot) handleCommand(text string) {
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		return
+	}
+	cmd, _, _ := strings.Cut(fields[0], "@")
+	if cmd != "/latest" {
+		return
+	}
+	n := 5
+	if len(fields) > 1 {
+		if v, err := strconv.Atoi(fields[1]); err == nil && v > 0 {
+			n = min(v, telegramLatestMax)
+		}
+	}
+
+	logs, err := store.List(n)
+	if err != nil {
+		b.sendMessage("Error: " + html.EscapeString(err.Error()))
+		return
+	}
+	if len(logs) == 0 {
+		b.sendMessage("まだ記録はありません")
+		return
+	}
+	var parts []string
+	for _, entry := range logs {
+		parts = append(parts, telegramEntryText(entry))
+	}
+	// 1 通に収まらない分は分けて送る
+	var msg string
+	for _, part := range parts {
+		if msg != "" && len(msg)+len(part)+2 > telegramTextLimit {
+			b.sendMessage(msg)
+			msg = ""
+		}
+		if msg != "" {
+			msg += "\n\n"
+		}
+		msg += part
+	}
+	if err := b.sendMessage(msg); err != nil {
+		fmt.Printf("Telegram Error: %v\n", err)
+	}
+}
+
+func mustJSON(v any) string {
+	data, _ := json.Marshal(v)
+	return string(data)
+}
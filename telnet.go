@@ -0,0 +1,162 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+const (
+	telnetIAC  = 255
+	telnetDONT = 254
+	telnetDO   = 253
+	telnetWONT = 252
+	telnetWILL = 251
+	telnetSB   = 250
+	telnetSE   = 240
+
+	telnetOptEcho = 1
+	telnetOptSGA  = 3
+
+	telnetMaxAttempts = 3
+)
+
+var telnetCommandNames = map[byte]string{telnetDONT: "DONT", telnetDO: "DO", telnetWONT: "WONT", telnetWILL: "WILL"}
+
+// telnetState は受信バイト列から IAC シーケンスを取り除きながら入力行を組み立てる
+type telnetState struct {
+	conn     net.Conn
+	raw      []byte
+	options  []string
+	text     strings.Builder
+	output   strings.Builder
+	line     []byte
+	password bool
+	iac      []byte // 処理途中の IAC シーケンス
+}
+
+// handleTelnet は最小限のオプション交渉を行い、login / Password プロンプトで入力を受けて全バイト列を記録する
+func handleTelnet(conn net.Conn) {
+	t := &telnetState{conn: conn}
+	defer t.save()
+
+	conn.Write([]byte{telnetIAC, telnetWILL, telnetOptEcho, telnetIAC, telnetWILL, telnetOptSGA})
+	t.send(fmt.Sprintf("\r\n%s login: ", domainHost()))
+
+	attempts := 0
+	buf := make([]byte, 1024)
+	for len(t.raw) < maxCaptureSize {
+		conn.SetReadDeadline(time.Now().Add(connTimeout))
+		n, err := conn.Read(buf)
+		for _, b := range buf[:n] {
+			t.raw = append(t.raw, b)
+			line, ok := t.feed(b)
+			if !ok {
+				continue
+			}
+			if !t.password {
+				t.text.WriteString("login: " + line + "\n")
+				t.password = true
+				t.send("\r\nPassword: ")
+				continue
+			}
+			t.text.WriteString("password: " + line + "\n")
+			t.password = false
+			if attempts++; attempts >= telnetMaxAttempts {
+				t.send("\r\nLogin incorrect\r\n")
+				return
+			}
+			t.send(fmt.Sprintf("\r\nLogin incorrect\r\n%s login: ", domainHost()))
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// feed は 1 バイト処理し、行が完成したらその行と true を返す
+func (t *telnetState) feed(b byte) (string, bool) {
+	if len(t.iac) > 0 || b == telnetIAC {
+		t.iac = append(t.iac, b)
+		t.handleIAC()
+		return "", false
+	}
+	switch b {
+	case '\r', '\n':
+		if len(t.line) == 0 {
+			return "", false
+		}
+		line := string(t.line)
+		t.line = t.line[:0]
+		return line, true
+	case 0:
+		return "", false
+	case 8, 127: // バックスペース
+		if len(t.line) > 0 {
+			t.line = t.line[:len(t.line)-1]
+		}
+		return "", false
+	}
+	t.line = append(t.line, b)
+	if !t.password {
+		t.conn.Write([]byte{b})
+	}
+	return "", false
+}
+
+// handleIAC は IAC シーケンスが揃った時点でオプションを記録し、ECHO と SGA 以外は拒否する
+func (t *telnetState) handleIAC() {
+	seq := t.iac
+	if len(seq) < 2 {
+		return
+	}
+	cmd := seq[1]
+	switch {
+	case cmd == telnetIAC: // エスケープされた 0xFF
+		t.iac = nil
+		t.line = append(t.line, telnetIAC)
+	case cmd >= telnetWILL && cmd <= telnetDONT:
+		if len(seq) < 3 {
+			return
+		}
+		opt := seq[2]
+		t.options = append(t.options, fmt.Sprintf("%s %d", telnetCommandNames[cmd], opt))
+		switch {
+		case cmd == telnetDO && opt != telnetOptEcho && opt != telnetOptSGA:
+			t.conn.Write([]byte{telnetIAC, telnetWONT, opt})
+		case cmd == telnetWILL:
+			t.conn.Write([]byte{telnetIAC, telnetDONT, opt})
+		}
+		t.iac = nil
+	case cmd == telnetSB:
+		n := len(seq)
+		if n >= 4 && seq[n-2] == telnetIAC && seq[n-1] == telnetSE {
+			t.options = append(t.options, fmt.Sprintf("SB %d %x", seq[2], seq[3:n-2]))
+			t.iac = nil
+		} else if n > 512 {
+			t.iac = nil
+		}
+	default:
+		t.options = append(t.options, fmt.Sprintf("CMD %d", cmd))
+		t.iac = nil
+	}
+}
+
+func (t *telnetState) send(s string) {
+	t.output.WriteString(s)
+	t.conn.Write([]byte(s))
+}
+
+func (t *telnetState) save() {
+	if len(t.line) > 0 {
+		t.text.WriteString("(partial) " + string(t.line) + "\n")
+	}
+	var b strings.Builder
+	if len(t.options) > 0 {
+		fmt.Fprintf(&b, "Options: %s\n\n", strings.Join(t.options, ", "))
+	}
+	fmt.Fprintf(&b, "%s\n--- raw (%d bytes) ---\n%s", t.text.String(), len(t.raw), hex.Dump(t.raw))
+	addLog(newLogEntry("telnet", remoteIP(t.conn), b.String(), t.output.String()))
+}
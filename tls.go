@@ -0,0 +1,110 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/acme/autocert"
+)
+
+const acmeChallengePrefix = "/.well-known/acme-challenge/"
+
+// acmeManager は -acme 指定時のみ設定される
+var acmeManager *autocert.Manager
+
+// newACMEManager は serverDomain の証明書を Let's Encrypt から取得・更新する Manager を作る
+func newACMEManager(cacheDir, email string) *autocert.Manager {
+	return &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		Cache:      autocert.DirCache(cacheDir),
+		HostPolicy: autocert.HostWhitelist(domainHost()),
+		Email:      email,
+	}
+}
+
+// handleACMEChallenge は HTTP-01 チャレンジのリクエストであれば応答して true を返す（ログには残さない）
+func handleACMEChallenge(w http.ResponseWriter, r *http.Request) bool {
+	if acmeManager == nil || !strings.HasPrefix(r.URL.Path, acmeChallengePrefix) {
+		return false
+	}
+	acmeManager.HTTPHandler(nil).ServeHTTP(w, r)
+	return true
+}
+
+// loadTLSConfig は証明書ファイルから TLS 設定を作る
+func loadTLSConfig(certFile, keyFile string) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Config{Certificates: []tls.Certificate{cert}}, nil
+}
+
+// selfSignedTLSConfig はメモリ上で自己署名証明書を生成する。
+// SAN には serverDomain と *.serverDomain に加えて extraSANs（DNS 名または IP）を含める
+func selfSignedTLSConfig(extraSANs []string) (*tls.Config, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+
+	host := domainHost()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: host},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().AddDate(1, 0, 0),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+	}
+	for _, san := range append([]string{host, "*." + host}, extraSANs...) {
+		if ip := net.ParseIP(san); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, san)
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, err
+	}
+	cert := tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+	return &tls.Config{Certificates: []tls.Certificate{cert}}, nil
+}
+
+// startTLSServer は HTTP と同じハンドラーで HTTPS を待ち受ける。
+// requestClientCert が true の場合、クライアント証明書を要求する（提示されなくても接続は続ける）
+func startTLSServer(port string, config *tls.Config, requestClientCert bool) {
+	if requestClientCert {
+		config.ClientAuth = tls.RequestClientCert
+	}
+	server := &http.Server{
+		Addr:        ":" + port,
+		Handler:     http.HandlerFunc(serveRoot),
+		TLSConfig:   config,
+		ConnContext: tlsConnContext,
+	}
+	ln, err := newListener(server.Addr)
+	if err == nil {
+		err = server.ServeTLS(tlsRecorderListener{ln}, "", "")
+	}
+	if err != nil {
+		fmt.Printf("TLS Error: %v\n", err)
+	}
+}
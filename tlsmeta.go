@@ -0,0 +1,326 @@
+package main
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const maxHandshakeCapture = 16 * 1024
+
+// TLSInfo は TLS ハンドシェイクから得たメタデータ
+type TLSInfo struct {
+	SNI            string          `json:"sni"`
+	Version        string          `json:"version"`
+	CipherSuite    string          `json:"cipher_suite"`
+	ALPN           string          `json:"alpn"`
+	OfferedCiphers []string        `json:"offered_ciphers"`
+	OfferedALPN    []string        `json:"offered_alpn"`
+	JA3            string          `json:"ja3"`
+	JA3Hash        string          `json:"ja3_hash"`
+	JA3S           string          `json:"ja3s"`
+	JA3SHash       string          `json:"ja3s_hash"`
+	ClientCert     *ClientCertInfo `json:"client_cert,omitempty"`
+}
+
+// ClientCertInfo はクライアントが提示した証明書（検証はしない）
+type ClientCertInfo struct {
+	Subject string   `json:"subject"`
+	Issuer  string   `json:"issuer"`
+	SANs    []string `json:"sans"`
+	PEM     string   `json:"pem"`
+}
+
+type tlsRecorderKey struct{}
+
+// tlsRecorderListener は接続の先頭バイト（ClientHello / ServerHello）を記録する接続を返す
+type tlsRecorderListener struct {
+	net.Listener
+}
+
+func (l tlsRecorderListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return &tlsRecordingConn{Conn: conn}, nil
+}
+
+type tlsRecordingConn struct {
+	net.Conn
+	mu       sync.Mutex
+	received []byte
+	sent     []byte
+}
+
+func (c *tlsRecordingConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	c.mu.Lock()
+	if len(c.received) < maxHandshakeCapture {
+		c.received = append(c.received, b[:min(n, maxHandshakeCapture-len(c.received))]...)
+	}
+	c.mu.Unlock()
+	return n, err
+}
+
+func (c *tlsRecordingConn) Write(b []byte) (int, error) {
+	c.mu.Lock()
+	if len(c.sent) < maxHandshakeCapture {
+		c.sent = append(c.sent, b[:min(len(b), maxHandshakeCapture-len(c.sent))]...)
+	}
+	c.mu.Unlock()
+	return c.Conn.Write(b)
+}
+
+// tlsConnContext は http.Server.ConnContext 用。記録中の接続をリクエストから参照できるようにする
+func tlsConnContext(ctx context.Context, c net.Conn) context.Context {
+	if tc, ok := c.(*tls.Conn); ok {
+		if rc, ok := tc.NetConn().(*tlsRecordingConn); ok {
+			return context.WithValue(ctx, tlsRecorderKey{}, rc)
+		}
+	}
+	return ctx
+}
+
+// requestTLSInfo は TLS のリクエストについてハンドシェイクのメタデータと JA3/JA3S を返す
+func requestTLSInfo(r *http.Request) *TLSInfo {
+	if r.TLS == nil {
+		return nil
+	}
+	info := &TLSInfo{
+		SNI:         r.TLS.ServerName,
+		Version:     tls.VersionName(r.TLS.Version),
+		CipherSuite: tls.CipherSuiteName(r.TLS.CipherSuite),
+		ALPN:        r.TLS.NegotiatedProtocol,
+	}
+
+	if len(r.TLS.PeerCertificates) > 0 {
+		info.ClientCert = clientCertInfo(r.TLS.PeerCertificates[0])
+	}
+
+	rc, ok := r.Context().Value(tlsRecorderKey{}).(*tlsRecordingConn)
+	if !ok {
+		return info
+	}
+	rc.mu.Lock()
+	received, sent := rc.received, rc.sent
+	rc.mu.Unlock()
+
+	if hello, err := parseClientHello(handshakeMessage(received)); err == nil {
+		for _, c := range hello.ciphers {
+			info.OfferedCiphers = append(info.OfferedCiphers, tls.CipherSuiteName(c))
+		}
+		info.OfferedALPN = hello.alpn
+		info.JA3 = strings.Join([]string{
+			strconv.Itoa(int(hello.version)),
+			joinUint16(hello.ciphers),
+			joinUint16(hello.extensions),
+			joinUint16(hello.groups),
+			joinUint16(hello.pointFormats),
+		}, ",")
+		info.JA3Hash = md5Hex(info.JA3)
+	}
+	if hello, err := parseServerHello(handshakeMessage(sent)); err == nil {
+		info.JA3S = strings.Join([]string{
+			strconv.Itoa(int(hello.version)),
+			strconv.Itoa(int(hello.cipher)),
+			joinUint16(hello.extensions),
+		}, ",")
+		info.JA3SHash = md5Hex(info.JA3S)
+	}
+	return info
+}
+
+func clientCertInfo(cert *x509.Certificate) *ClientCertInfo {
+	info := &ClientCertInfo{
+		Subject: cert.Subject.String(),
+		Issuer:  cert.Issuer.String(),
+		PEM:     string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})),
+	}
+	info.SANs = append(info.SANs, cert.DNSNames...)
+	for _, ip := range cert.IPAddresses {
+		info.SANs = append(info.SANs, ip.String())
+	}
+	info.SANs = append(info.SANs, cert.EmailAddresses...)
+	for _, u := range cert.URIs {
+		info.SANs = append(info.SANs, u.String())
+	}
+	return info
+}
+
+type clientHello struct {
+	version      uint16
+	ciphers      []uint16
+	extensions   []uint16
+	groups       []uint16
+	pointFormats []uint16
+	alpn         []string
+}
+
+type serverHello struct {
+	version    uint16
+	cipher     uint16
+	extensions []uint16
+}
+
+// handshakeMessage は先頭の連続したハンドシェイクレコードからペイロードを連結して返す
+func handshakeMessage(data []byte) []byte {
+	var msg []byte
+	for len(data) >= 5 && data[0] == 0x16 {
+		n := int(binary.BigEndian.Uint16(data[3:5]))
+		if len(data) < 5+n {
+			n = len(data) - 5
+		}
+		msg = append(msg, data[5:5+n]...)
+		data = data[5+n:]
+	}
+	return msg
+}
+
+// isGREASE は RFC 8701 の GREASE 値（JA3 では除外する）かを判定する
+func isGREASE(v uint16) bool {
+	return v&0x0f0f == 0x0a0a && v>>8 == v&0xff
+}
+
+// tlsReader はハンドシェイクメッセージを読むための小さなカーソル
+type tlsReader struct {
+	data []byte
+	err  error
+}
+
+func (r *tlsReader) bytes(n int) []byte {
+	if r.err != nil || n > len(r.data) {
+		r.err = errors.New("truncated handshake")
+		return nil
+	}
+	b := r.data[:n]
+	r.data = r.data[n:]
+	return b
+}
+
+func (r *tlsReader) u8() int {
+	if b := r.bytes(1); b != nil {
+		return int(b[0])
+	}
+	return 0
+}
+
+func (r *tlsReader) u16() int {
+	if b := r.bytes(2); b != nil {
+		return int(binary.BigEndian.Uint16(b))
+	}
+	return 0
+}
+
+func (r *tlsReader) u24() int {
+	if b := r.bytes(3); b != nil {
+		return int(b[0])<<16 | int(b[1])<<8 | int(b[2])
+	}
+	return 0
+}
+
+func uint16List(b []byte, skipGREASE bool) []uint16 {
+	var out []uint16
+	for i := 0; i+1 < len(b); i += 2 {
+		v := binary.BigEndian.Uint16(b[i:])
+		if skipGREASE && isGREASE(v) {
+			continue
+		}
+		out = append(out, v)
+	}
+	return out
+}
+
+func parseClientHello(msg []byte) (*clientHello, error) {
+	r := &tlsReader{data: msg}
+	if r.u8() != 1 {
+		return nil, errors.New("not a ClientHello")
+	}
+	r.data = r.bytes(r.u24())
+	hello := &clientHello{version: uint16(r.u16())}
+	r.bytes(32)
+	r.bytes(r.u8())
+	hello.ciphers = uint16List(r.bytes(r.u16()), true)
+	r.bytes(r.u8())
+	if r.err != nil {
+		return nil, r.err
+	}
+	if len(r.data) == 0 {
+		return hello, nil
+	}
+
+	exts := &tlsReader{data: r.bytes(r.u16())}
+	for exts.err == nil && len(exts.data) >= 4 {
+		typ := uint16(exts.u16())
+		body := exts.bytes(exts.u16())
+		if !isGREASE(typ) {
+			hello.extensions = append(hello.extensions, typ)
+		}
+		switch typ {
+		case 10: // supported_groups
+			if len(body) >= 2 {
+				hello.groups = uint16List(body[2:], true)
+			}
+		case 11: // ec_point_formats
+			if len(body) >= 1 {
+				for _, f := range body[1:] {
+					hello.pointFormats = append(hello.pointFormats, uint16(f))
+				}
+			}
+		case 16: // ALPN
+			alpn := &tlsReader{data: body}
+			alpn.data = alpn.bytes(alpn.u16())
+			for alpn.err == nil && len(alpn.data) > 0 {
+				hello.alpn = append(hello.alpn, string(alpn.bytes(alpn.u8())))
+			}
+		}
+	}
+	return hello, r.err
+}
+
+func parseServerHello(msg []byte) (*serverHello, error) {
+	r := &tlsReader{data: msg}
+	if r.u8() != 2 {
+		return nil, errors.New("not a ServerHello")
+	}
+	r.data = r.bytes(r.u24())
+	hello := &serverHello{version: uint16(r.u16())}
+	r.bytes(32)
+	r.bytes(r.u8())
+	hello.cipher = uint16(r.u16())
+	r.u8()
+	if r.err != nil {
+		return nil, r.err
+	}
+	if len(r.data) > 0 {
+		exts := &tlsReader{data: r.bytes(r.u16())}
+		for exts.err == nil && len(exts.data) >= 4 {
+			hello.extensions = append(hello.extensions, uint16(exts.u16()))
+			exts.bytes(exts.u16())
+		}
+	}
+	return hello, r.err
+}
+
+func joinUint16(vs []uint16) string {
+	parts := make([]string, len(vs))
+	for i, v := range vs {
+		parts[i] = strconv.Itoa(int(v))
+	}
+	return strings.Join(parts, "-")
+}
+
+func md5Hex(s string) string {
+	sum := md5.Sum([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const apiTokenPrefix = "ssrf_"
+
+// apiToken は発行した API トークン。平文は発行時に一度だけ返し、保存するのはハッシュだけ
+type apiToken struct {
+	ID       string    `json:"id"`
+	Name     string    `json:"name"`
+	Hash     string    `json:"hash,omitempty"` // 平文の SHA-256。一覧では空にする
+	Prefix   string    `json:"prefix"`         // 一覧で見分けるための先頭部分
+	Role     string    `json:"role,omitempty"` // viewer か operator。空は operator（ロール導入前のトークン）
+	Created  time.Time `json:"created"`
+	LastUsed time.Time `json:"last_used,omitzero"`
+}
+
+// tokenStore は API トークンの一覧。path が空ならメモリだけに持つ
+type tokenStore struct {
+	mu     sync.Mutex
+	path   string
+	tokens []apiToken
+}
+
+var apiTokens = &tokenStore{}
+
+// load は -token-file を読む。ファイルがなければ空から始める
+func (s *tokenStore) load(path string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.path = path
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, &s.tokens)
+}
+
+// save は呼び出し側でロックを取っておく
+func (s *tokenStore) save() error {
+	return writeJSONFile(s.path, s.tokens)
+}
+
+func (t apiToken) role() role {
+	if r, ok := parseRole(t.Role); ok {
+		return r
+	}
+	return roleOperator
+}
+
+func (s *tokenStore) create(name, roleName string) (string, apiToken, error) {
+	secret := make([]byte, 24)
+	id := make([]byte, 4)
+	rand.Read(secret)
+	rand.Read(id)
+	raw := apiTokenPrefix + hex.EncodeToString(secret)
+	token := apiToken{
+		ID:      hex.EncodeToString(id),
+		Name:    name,
+		Role:    roleName,
+		Hash:    sha256Hex([]byte(raw)),
+		Prefix:  raw[:len(apiTokenPrefix)+6],
+		Created: time.Now(),
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokens = append(s.tokens, token)
+	return raw, token, s.save()
+}
+
+func (s *tokenStore) revoke(id string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := len(s.tokens)
+	s.tokens = slices.DeleteFunc(s.tokens, func(t apiToken) bool { return t.ID == id })
+	if len(s.tokens) == n {
+		return false, nil
+	}
+	return true, s.save()
+}
+
+func (s *tokenStore) list() []apiToken {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.tokens)
+}
+
+// check は raw が有効なトークンならそれを返し、最終利用時刻を更新する（ファイルには書かない）
+func (s *tokenStore) check(raw string) (apiToken, bool) {
+	if !strings.HasPrefix(raw, apiTokenPrefix) {
+		return apiToken{}, false
+	}
+	hash := sha256Hex([]byte(raw))
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.tokens {
+		if s.tokens[i].Hash == hash {
+			s.tokens[i].LastUsed = time.Now()
+			return s.tokens[i], true
+		}
+	}
+	return apiToken{}, false
+}
+
+// requestToken は Authorization: Bearer か X-API-Key からトークンを取り出す
+func requestToken(r *http.Request) string {
+	if scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " "); ok && strings.EqualFold(scheme, "Bearer") {
+		return strings.TrimSpace(token)
+	}
+	return r.Header.Get("X-API-Key")
+}
+
+// handleTokens は GET /admin/tokens で一覧を、POST /admin/tokens で {"name": "...", "role": "viewer"} のトークンを発行する。
+// トークンは /api と /metrics にしか使えないので、ロールは viewer か operator（省略時）
+// 平文のトークンは発行時のレスポンスにしか含まれない
+func handleTokens(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodGet {
+		tokens := apiTokens.list()
+		for i := range tokens {
+			tokens[i].Hash = ""
+		}
+		writeJSON(w, tokens)
+		return
+	}
+	var body struct {
+		Name string `json:"name"`
+		Role string `json:"role"`
+	}
+	if err := json.NewDecoder(io.LimitReader(r.Body, 4096)).Decode(&body); err != nil || strings.TrimSpace(body.Name) == "" {
+		http.Error(w, "name is required", http.StatusBadRequest)
+		return
+	}
+	ro := roleOperator
+	if body.Role != "" {
+		var ok bool
+		if ro, ok = parseRole(body.Role); !ok || ro == roleAdmin {
+			http.Error(w, "role must be viewer or operator", http.StatusBadRequest)
+			return
+		}
+	}
+	raw, token, err := apiTokens.create(strings.TrimSpace(body.Name), ro.String())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	writeJSON(w, map[string]any{"id": token.ID, "name": token.Name, "role": token.Role, "token": raw, "created": token.Created})
+}
+
+// handleRevokeToken は DELETE /admin/tokens/{id} でトークンを無効にする
+func handleRevokeToken(w http.ResponseWriter, r *http.Request) {
+	ok, err := apiTokens.revoke(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+)
+
+// reverseProxy は -upstream 指定時のみ設定される
+var reverseProxy *httputil.ReverseProxy
+
+// upstreamTransport は環境変数のプロキシ設定を使わずに直接接続する。上流は内部のホストのことが多いので
+// フォワードプロキシと違って宛先は制限しない
+var upstreamTransport = func() *http.Transport {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.Proxy = nil
+	return t
+}()
+
+type upstreamCaptureKey struct{}
+
+// upstreamCapture は ModifyResponse / ErrorHandler からハンドラーへ応答内容を受け渡す
+type upstreamCapture struct {
+	response string
+	body     *captureBuffer // 流したボディの先頭。全体を溜めると SSE などが上流の終わりまで届かない
+}
+
+func newReverseProxy(target *url.URL) *httputil.ReverseProxy {
+	return &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			pr.SetURL(target)
+			pr.SetXForwarded()
+		},
+		Transport: upstreamTransport,
+		ModifyResponse: func(resp *http.Response) error {
+			if c, ok := resp.Request.Context().Value(upstreamCaptureKey{}).(*upstreamCapture); ok {
+				dump, _ := httputil.DumpResponse(resp, false)
+				c.response = string(dump)
+				resp.Body, c.body = captureBody(resp.Body)
+			}
+			return nil
+		},
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			if c, ok := r.Context().Value(upstreamCaptureKey{}).(*upstreamCapture); ok {
+				c.response = fmt.Sprintf("HTTP/1.1 502 Bad Gateway\n\n%v", err)
+			}
+			http.Error(w, "Bad Gateway", http.StatusBadGateway)
+		},
+	}
+}
+
+// handleUpstream はリクエストを上流へそのまま中継し、リクエストとレスポンスの組を記録する
+func handleUpstream(w http.ResponseWriter, r *http.Request) {
+	clientIP := requestClientIP(r)
+	requestDump, _ := httputil.DumpRequest(r, true)
+
+	capture := &upstreamCapture{}
+	ctx := context.WithValue(r.Context(), upstreamCaptureKey{}, capture)
+	reverseProxy.ServeHTTP(w, r.WithContext(ctx))
+
+	response := capture.response
+	if capture.body != nil {
+		response += string(capture.body.Bytes())
+	}
+	entry := newLogEntry("upstream", clientIP, string(requestDump), response)
+	entry.ProtoVersion = r.Proto
+	entry.TLS = requestTLSInfo(r)
+	addLog(entry)
+}
@@ -0,0 +1,319 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// role は管理用ユーザーとトークンの権限。大きいほど強い
+type role int
+
+const (
+	roleNone role = iota
+	roleViewer
+	roleOperator
+	roleAdmin
+)
+
+var roleNames = map[role]string{roleViewer: "viewer", roleOperator: "operator", roleAdmin: "admin"}
+
+func (r role) String() string { return roleNames[r] }
+
+func parseRole(s string) (role, bool) {
+	for r, name := range roleNames {
+		if strings.EqualFold(s, name) {
+			return r, true
+		}
+	}
+	return roleNone, false
+}
+
+// adminUser は -user-file のユーザー。パスワードは bcrypt のハッシュだけを持つ
+type adminUser struct {
+	Name    string    `json:"name"`
+	Hash    string    `json:"hash,omitempty"`
+	Role    string    `json:"role"`
+	Created time.Time `json:"created"`
+}
+
+// userStore は管理用ユーザーの一覧。path が空ならメモリだけに持つ
+type userStore struct {
+	mu    sync.Mutex
+	path  string
+	users []adminUser
+}
+
+var adminUsers = &userStore{}
+
+// load は -user-file を読む。ファイルがなければ空から始める
+func (s *userStore) load(path string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.path = path
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, &s.users); err != nil {
+		return err
+	}
+	// -admin-user と同じ名前のセッションは admin として扱うので、同名のユーザーは置けない
+	if adminAuth.user != "" && slices.ContainsFunc(s.users, func(u adminUser) bool { return u.Name == adminAuth.user }) {
+		return fmt.Errorf("%s: user %q has the same name as -admin-user", path, adminAuth.user)
+	}
+	return nil
+}
+
+// save は呼び出し側でロックを取っておく
+func (s *userStore) save() error {
+	return writeJSONFile(s.path, s.users)
+}
+
+func (s *userStore) empty() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.users) == 0
+}
+
+func (s *userStore) list() []adminUser {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.users)
+}
+
+// put はユーザーを追加する。create が false なら既存ユーザーのパスワードかロールを変える（空の値は変えない）
+func (s *userStore) put(name, password, roleName string, create bool) (adminUser, error) {
+	var hash []byte
+	if password != "" {
+		var err error
+		if hash, err = bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost); err != nil {
+			return adminUser{}, err
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := slices.IndexFunc(s.users, func(u adminUser) bool { return u.Name == name })
+	switch {
+	case create && (i >= 0 || name == adminAuth.user):
+		return adminUser{}, errUserExists
+	case !create && i < 0:
+		return adminUser{}, errUserNotFound
+	case create:
+		s.users = append(s.users, adminUser{Name: name, Created: time.Now()})
+		i = len(s.users) - 1
+	}
+	if hash != nil {
+		s.users[i].Hash = string(hash)
+	}
+	if roleName != "" {
+		s.users[i].Role = roleName
+	}
+	return s.users[i], s.save()
+}
+
+func (s *userStore) remove(name string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := len(s.users)
+	s.users = slices.DeleteFunc(s.users, func(u adminUser) bool { return u.Name == name })
+	if len(s.users) == n {
+		return false, nil
+	}
+	return true, s.save()
+}
+
+// check は name と password が一致すればそのロールを返す
+func (s *userStore) check(name, password string) role {
+	s.mu.Lock()
+	i := slices.IndexFunc(s.users, func(u adminUser) bool { return u.Name == name })
+	var u adminUser
+	if i >= 0 {
+		u = s.users[i]
+	}
+	s.mu.Unlock()
+	if i < 0 || bcrypt.CompareHashAndPassword([]byte(u.Hash), []byte(password)) != nil {
+		return roleNone
+	}
+	r, _ := parseRole(u.Role)
+	return r
+}
+
+// role は name のユーザーのロール。いなければ roleNone
+func (s *userStore) role(name string) role {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, u := range s.users {
+		if u.Name == name {
+			r, _ := parseRole(u.Role)
+			return r
+		}
+	}
+	return roleNone
+}
+
+var (
+	errUserExists   = errors.New("user already exists")
+	errUserNotFound = errors.New("user not found")
+)
+
+// principal は認証できた相手。コンテキストに入れてハンドラーから参照する
+type principal struct {
+	Name    string
+	Role    role
+	Session bool // ログインフォームから入ったか。管理画面にログアウトを出す
+}
+
+// CanOperate は管理画面でクリアなどのボタンを出すかに使う
+func (p principal) CanOperate() bool { return p.Role >= roleOperator }
+
+type principalKey struct{}
+
+func withPrincipal(r *http.Request, p principal) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), principalKey{}, p))
+}
+
+// requestPrincipal は認証を使っていなければ名前なしの admin を返す
+func requestPrincipal(r *http.Request) principal {
+	if p, ok := r.Context().Value(principalKey{}).(principal); ok {
+		return p
+	}
+	return principal{Role: roleAdmin}
+}
+
+// requiredRole はリクエストに必要なロール。ユーザーとトークンの管理は admin、状態を変える操作は operator、それ以外は viewer。
+// 既読にするのは管理画面でエントリを開くだけで送られるので viewer でもよい
+func requiredRole(r *http.Request) role {
+	switch {
+	case r.URL.Path == "/admin/users" || strings.HasPrefix(r.URL.Path, "/admin/users/"),
+		r.URL.Path == "/admin/tokens" || strings.HasPrefix(r.URL.Path, "/admin/tokens/"):
+		return roleAdmin
+	case readStatePath(r.URL.Path):
+		return roleViewer
+	case unsafeMethod(r.Method):
+		return roleOperator
+	}
+	return roleViewer
+}
+
+// readStatePath は既読・未読を切り替える /api/logs/{id}/read と /api/logs/read
+func readStatePath(path string) bool {
+	rest, ok := strings.CutPrefix(path, "/api/logs/")
+	return ok && (rest == "read" || strings.HasSuffix(rest, "/read") && !strings.Contains(strings.TrimSuffix(rest, "/read"), "/"))
+}
+
+// handleUsers は GET /admin/users で一覧を、POST /admin/users で {"name", "password", "role"} のユーザーを作る。
+// 作れるのは -admin-user か既存のユーザーで認証しているときだけ
+func handleUsers(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodGet {
+		users := adminUsers.list()
+		for i := range users {
+			users[i].Hash = ""
+		}
+		writeJSON(w, users)
+		return
+	}
+	// 認証を使っていなければ誰でもここまで来られるので、最初の admin は -admin-user で作ってもらう
+	if !authEnabled() {
+		http.Error(w, "start with -admin-user to create users", http.StatusForbidden)
+		return
+	}
+	body, ok := decodeUserBody(w, r)
+	if !ok {
+		return
+	}
+	body.Name = strings.TrimSpace(body.Name)
+	if body.Name == "" || body.Password == "" || body.Role == "" {
+		http.Error(w, "name, password and role are required", http.StatusBadRequest)
+		return
+	}
+	user, err := adminUsers.put(body.Name, body.Password, body.Role, true)
+	if errors.Is(err, errUserExists) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	user.Hash = ""
+	w.WriteHeader(http.StatusCreated)
+	writeJSON(w, user)
+}
+
+// handleUser は PUT /admin/users/{name} で {"password"} か {"role"} を変え、DELETE で削除する
+func handleUser(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if r.Method == http.MethodDelete {
+		ok, err := adminUsers.remove(name)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		sessions.removeUser(name)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	body, ok := decodeUserBody(w, r)
+	if !ok {
+		return
+	}
+	user, err := adminUsers.put(name, body.Password, body.Role, false)
+	if errors.Is(err, errUserNotFound) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if body.Password != "" {
+		sessions.removeUser(name)
+	}
+	user.Hash = ""
+	writeJSON(w, user)
+}
+
+type userBody struct {
+	Name     string `json:"name"`
+	Password string `json:"password"`
+	Role     string `json:"role"`
+}
+
+func decodeUserBody(w http.ResponseWriter, r *http.Request) (userBody, bool) {
+	var body userBody
+	if err := json.NewDecoder(io.LimitReader(r.Body, 4096)).Decode(&body); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return body, false
+	}
+	if body.Role != "" {
+		ro, ok := parseRole(body.Role)
+		if !ok {
+			http.Error(w, "role must be viewer, operator or admin", http.StatusBadRequest)
+			return body, false
+		}
+		body.Role = ro.String()
+	}
+	if body.Password != "" && len(body.Password) < 8 {
+		http.Error(w, "password must be at least 8 characters", http.StatusBadRequest)
+		return body, false
+	}
+	return body, true
+}
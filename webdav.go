@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const webdavMethods = "OPTIONS, GET, HEAD, PUT, DELETE, PROPFIND, PROPPATCH, MKCOL, COPY, MOVE, LOCK, UNLOCK"
+
+// emulateWebDAV は WebDAV のメソッドに応答する。PROPFIND には 207 Multi-Status を返し、
+// MKCOL / PUT / MOVE などは成功したように見せる。XML ボディはリクエストのダンプにそのまま残る
+func emulateWebDAV(r *http.Request) *mockResponse {
+	const tag = "webdav"
+	var res *mockResponse
+	switch r.Method {
+	case http.MethodOptions:
+		res = textResponse(http.StatusOK, "", tag)
+	case "PROPFIND":
+		res = xmlResponse(http.StatusMultiStatus, webdavMultistatus(r), tag)
+	case "PROPPATCH":
+		res = xmlResponse(http.StatusMultiStatus, fmt.Sprintf(`<?xml version="1.0" encoding="utf-8"?>
+<D:multistatus xmlns:D="DAV:"><D:response><D:href>%s</D:href><D:propstat><D:prop/><D:status>HTTP/1.1 200 OK</D:status></D:propstat></D:response></D:multistatus>
+`, html.EscapeString(r.URL.Path)), tag)
+	case "MKCOL", http.MethodPut:
+		res = textResponse(http.StatusCreated, "", tag)
+		res.Header.Set("Location", r.URL.Path)
+	case "MOVE", "COPY":
+		res = textResponse(http.StatusCreated, "", tag)
+		if dst := r.Header.Get("Destination"); dst != "" {
+			res.Header.Set("Location", dst)
+		}
+	case http.MethodDelete, "UNLOCK":
+		res = textResponse(http.StatusNoContent, "", tag)
+	case "LOCK":
+		token := "opaquelocktoken:" + randomString(8, "0123456789abcdef") + "-" + randomString(4, "0123456789abcdef")
+		res = xmlResponse(http.StatusOK, fmt.Sprintf(`<?xml version="1.0" encoding="utf-8"?>
+<D:prop xmlns:D="DAV:"><D:lockdiscovery><D:activelock><D:locktype><D:write/></D:locktype><D:lockscope><D:exclusive/></D:lockscope><D:depth>0</D:depth><D:timeout>Second-3600</D:timeout><D:locktoken><D:href>%s</D:href></D:locktoken></D:activelock></D:lockdiscovery></D:prop>
+`, token), tag)
+		res.Header.Set("Lock-Token", "<"+token+">")
+	default:
+		return nil
+	}
+	res.Header.Set("DAV", "1, 2")
+	res.Header.Set("Allow", webdavMethods)
+	res.Header.Set("MS-Author-Via", "DAV")
+	return res
+}
+
+// webdavMultistatus は要求されたパスを、末尾が / ならコレクション、それ以外ならファイルとして返す。
+// Depth: 1 のコレクションにはダミーのファイルを 1 件含める
+func webdavMultistatus(r *http.Request) string {
+	modified := time.Now().UTC().Add(-72 * time.Hour).Format(http.TimeFormat)
+	entry := func(href string, collection bool) string {
+		prop := `<D:resourcetype/><D:getcontentlength>1024</D:getcontentlength><D:getcontenttype>text/plain</D:getcontenttype>`
+		if collection {
+			prop = `<D:resourcetype><D:collection/></D:resourcetype>`
+		}
+		return fmt.Sprintf(`<D:response><D:href>%s</D:href><D:propstat><D:prop><D:displayname>%s</D:displayname><D:getlastmodified>%s</D:getlastmodified>%s</D:prop><D:status>HTTP/1.1 200 OK</D:status></D:propstat></D:response>
+`, html.EscapeString(href), html.EscapeString(webdavName(href)), modified, prop)
+	}
+
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="utf-8"?>
+<D:multistatus xmlns:D="DAV:">
+`)
+	collection := strings.HasSuffix(r.URL.Path, "/")
+	b.WriteString(entry(r.URL.Path, collection))
+	if collection && r.Header.Get("Depth") != "0" {
+		b.WriteString(entry(r.URL.Path+"notes.txt", false))
+	}
+	b.WriteString("</D:multistatus>\n")
+	return b.String()
+}
+
+func webdavName(href string) string {
+	name := strings.TrimSuffix(href, "/")
+	return name[strings.LastIndex(name, "/")+1:]
+}
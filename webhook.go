@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"text/template"
+	"time"
+)
+
+// newWebhookNotifier は新しいエントリを JSON のまま url に POST する。
+// secret があれば "タイムスタンプ.本文" の HMAC-SHA256 を X-SSRF-Signature に付ける。
+// tmpl があれば、その結果を本文にする
+func newWebhookNotifier(url, secret string, tmpl *template.Template) *notifier {
+	return newNotifier("Webhook", func(entry LogEntry) error {
+		body, err := json.Marshal(entry)
+		if tmpl != nil {
+			var text string
+			text, err = renderNotify(tmpl, entry)
+			body = []byte(text)
+		}
+		if err != nil {
+			return err
+		}
+		req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("User-Agent", "goSsrfServ-Webhook")
+		if secret != "" {
+			ts := strconv.FormatInt(time.Now().Unix(), 10)
+			req.Header.Set("X-SSRF-Timestamp", ts)
+			req.Header.Set("X-SSRF-Signature", "sha256="+webhookSignature(secret, ts, body))
+		}
+		resp, err := notifyClient.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode/100 != 2 {
+			msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+			return fmt.Errorf("POST %s: %s: %s", url, resp.Status, bytes.TrimSpace(msg))
+		}
+		return nil
+	})
+}
+
+// webhookSignature は受信側で検証するときと同じ手順で署名を計算する。
+// タイムスタンプを含めるので、受信側で古いものを捨てればリプレイも防げる
+func webhookSignature(secret, timestamp string, body []byte) string {
+	return hex.EncodeToString(hmacSHA256([]byte(secret), timestamp+"."+string(body)))
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+const (
+	wsOpContinuation = 0x0
+	wsOpText         = 0x1
+	wsOpBinary       = 0x2
+	wsOpClose        = 0x8
+	wsOpPing         = 0x9
+	wsOpPong         = 0xA
+)
+
+var wsOpNames = map[byte]string{
+	wsOpContinuation: "continuation",
+	wsOpText:         "text",
+	wsOpBinary:       "binary",
+	wsOpClose:        "close",
+	wsOpPing:         "ping",
+	wsOpPong:         "pong",
+}
+
+func isWebSocketUpgrade(r *http.Request) bool {
+	return strings.EqualFold(r.Header.Get("Upgrade"), "websocket") &&
+		strings.Contains(strings.ToLower(r.Header.Get("Connection")), "upgrade")
+}
+
+// handleWebSocket はハンドシェイクを完了させ、受信したフレームを元のリクエストに紐づくエントリとして記録する
+func handleWebSocket(w http.ResponseWriter, r *http.Request, protocol, clientIP, requestDump string) {
+	key := r.Header.Get("Sec-WebSocket-Key")
+	hj, ok := w.(http.Hijacker)
+	if key == "" || !ok {
+		http.Error(w, "Bad WebSocket handshake", http.StatusBadRequest)
+		return
+	}
+	conn, rw, err := hj.Hijack()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(connTimeout))
+
+	sum := sha1.Sum([]byte(key + websocketGUID))
+	handshake := "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(sum[:]) + "\r\n\r\n"
+	rw.WriteString(handshake)
+	rw.Flush()
+
+	parent := newLogEntry(protocol, clientIP, requestDump, handshake)
+	parent = addLog(parent)
+
+	for {
+		opcode, payload, err := readWebSocketFrame(rw.Reader)
+		if err != nil {
+			return
+		}
+
+		frame := fmt.Sprintf("Opcode: %s\nLength: %d\n\n%s", wsOpNames[opcode], len(payload), dumpBytes(payload))
+		entry := newLogEntry(protocol, clientIP, frame, "")
+		entry.ParentID = parent.ID
+
+		switch opcode {
+		case wsOpPing:
+			writeWebSocketFrame(conn, wsOpPong, payload)
+			entry.RawResponse = "pong"
+		case wsOpClose:
+			writeWebSocketFrame(conn, wsOpClose, payload)
+			entry.RawResponse = "close"
+			addLog(entry)
+			return
+		}
+		addLog(entry)
+	}
+}
+
+// readWebSocketFrame はフレームを 1 つ読み、マスクを外したペイロードを返す
+func readWebSocketFrame(r *bufio.Reader) (byte, []byte, error) {
+	var header [2]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return 0, nil, err
+	}
+	opcode := header[0] & 0x0f
+	masked := header[1]&0x80 != 0
+	length := uint64(header[1] & 0x7f)
+
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(r, ext[:]); err != nil {
+			return 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(r, ext[:]); err != nil {
+			return 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(ext[:])
+	}
+	if length > maxCaptureSize {
+		return 0, nil, errors.New("frame too large")
+	}
+
+	var mask [4]byte
+	if masked {
+		if _, err := io.ReadFull(r, mask[:]); err != nil {
+			return 0, nil, err
+		}
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return 0, nil, err
+	}
+	if masked {
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+	}
+	return opcode, payload, nil
+}
+
+// writeWebSocketFrame はサーバーからの制御フレーム（マスクなし、125 バイト以下）を送信する
+func writeWebSocketFrame(w io.Writer, opcode byte, payload []byte) {
+	if len(payload) > 125 {
+		payload = payload[:125]
+	}
+	w.Write(append([]byte{0x80 | opcode, byte(len(payload))}, payload...))
+}